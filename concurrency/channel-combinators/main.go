@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// --- Helpers ---
+
+// count sends 1..n, 'every' apart, and closes.
+func count(n int, every time.Duration) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 1; i <= n; i++ {
+			ch <- i
+			time.Sleep(every)
+		}
+	}()
+	return ch
+}
+
+// burst sends the values of each burst back to back, pausing 'gap'
+// after each burst, and closes.
+func burst(gap time.Duration, bursts ...[]string) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for _, b := range bursts {
+			for _, v := range b {
+				ch <- v
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(gap)
+		}
+	}()
+	return ch
+}
+
+// collect drains ch into a slice.
+func collect[T any](ch <-chan T) []T {
+	var vs []T
+	for v := range ch {
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+// --- One Example per Combinator ---
+
+func exampleTee(ctx context.Context) {
+	fmt.Println("Tee: both readers see every value")
+	a, b := channels.Tee(ctx, count(4, 0))
+
+	var wg sync.WaitGroup
+	var gotA, gotB []int
+	wg.Add(2)
+	go func() { defer wg.Done(); gotA = collect(a) }()
+	go func() { defer wg.Done(); gotB = collect(b) }()
+	wg.Wait()
+	fmt.Printf("   a=%v b=%v\n", gotA, gotB)
+}
+
+func exampleBroadcast(ctx context.Context) {
+	fmt.Println("Broadcast: one fast and one slow subscriber, per policy")
+	for _, policy := range []struct {
+		name string
+		p    channels.SlowPolicy
+	}{{"Block", channels.Block}, {"Drop", channels.Drop}, {"Buffer", channels.Buffer}} {
+		start := time.Now()
+		b := channels.Broadcast(ctx, count(20, time.Millisecond), 2, policy.p, 1)
+
+		var wg sync.WaitGroup
+		var fast, slow []int
+		var fastDone time.Duration
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			fast = collect(b.Subscriber(0))
+			fastDone = time.Since(start)
+		}()
+		go func() {
+			defer wg.Done()
+			for v := range b.Subscriber(1) {
+				slow = append(slow, v)
+				time.Sleep(2 * time.Millisecond)
+			}
+		}()
+		wg.Wait()
+
+		fmt.Printf("   %-6s fast got %2d (dropped %2d) in %5v, slow got %2d (dropped %2d)\n", policy.name,
+			len(fast), b.Dropped(0), fastDone.Round(time.Millisecond), len(slow), b.Dropped(1))
+	}
+}
+
+func exampleZip(ctx context.Context) {
+	fmt.Println("Zip: pairs in order, until the shorter input ends")
+	names := make(chan string, 3)
+	names <- "alice"
+	names <- "bob"
+	names <- "carol"
+	close(names)
+	for p := range channels.Zip(ctx, count(2, time.Millisecond), names) {
+		fmt.Printf("   %d -> %s\n", p.First, p.Second)
+	}
+}
+
+func exampleBatch(ctx context.Context) {
+	fmt.Println("Batch: up to 4 values, or whatever arrived within 15ms")
+	// 10 quick values, then 3 slow ones: full batches first, then timed-out ones.
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 13; i++ {
+			in <- i
+			if i >= 10 {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}()
+	fmt.Printf("   %v\n", collect(channels.Batch(ctx, in, 4, 15*time.Millisecond)))
+}
+
+func exampleWindow(ctx context.Context) {
+	fmt.Println("Window: tumbling (size 3, step 3) and sliding (size 3, step 1)")
+	fmt.Printf("   tumbling %v\n", collect(channels.Window(ctx, count(7, 0), 3, 3)))
+	fmt.Printf("   sliding  %v\n", collect(channels.Window(ctx, count(5, 0), 3, 1)))
+}
+
+func exampleDebounce(ctx context.Context) {
+	fmt.Println("Debounce: keystrokes collapse into the last one after 20ms of quiet")
+	keys := burst(40*time.Millisecond, []string{"g", "go", "gor"}, []string{"goro", "gorou", "goroutine"})
+	fmt.Printf("   %q\n", collect(channels.Debounce(ctx, keys, 20*time.Millisecond)))
+}
+
+func exampleThrottle(ctx context.Context) {
+	fmt.Println("Throttle: at most one value per 10ms out of one every 3ms")
+	got := collect(channels.Throttle(ctx, count(20, 3*time.Millisecond), 10*time.Millisecond))
+	fmt.Printf("   kept %d of 20: %v\n", len(got), got)
+}
+
+func exampleCancel() {
+	fmt.Println("Cancellation: every combinator closes its output when ctx is done")
+	ctx, cancel := context.WithCancel(context.Background())
+	never := make(chan int) // Never sends, never closes.
+
+	a, b := channels.Tee(ctx, never)
+	outs := []<-chan int{a, b,
+		channels.Broadcast(ctx, never, 1, channels.Buffer, 0).Subscriber(0),
+		channels.Debounce(ctx, never, time.Millisecond),
+		channels.Throttle(ctx, never, time.Millisecond),
+	}
+	batches := []<-chan []int{
+		channels.Batch(ctx, never, 10, time.Millisecond),
+		channels.Window(ctx, never, 2, 1),
+	}
+	zipped := channels.Zip(ctx, never, never)
+
+	cancel()
+	for _, ch := range outs {
+		collect(ch)
+	}
+	for _, ch := range batches {
+		collect(ch)
+	}
+	collect(zipped)
+	fmt.Println("   all outputs closed")
+}
+
+// --- Main Function to Run the Examples ---
+
+func main() {
+	before := runtime.NumGoroutine()
+	ctx := context.Background()
+
+	exampleTee(ctx)
+	exampleBroadcast(ctx)
+	exampleZip(ctx)
+	exampleBatch(ctx)
+	exampleWindow(ctx)
+	exampleDebounce(ctx)
+	exampleThrottle(ctx)
+	exampleCancel()
+
+	time.Sleep(10 * time.Millisecond)
+	fmt.Printf("\nGoroutines before: %d, after: %d\n", before, runtime.NumGoroutine())
+}
@@ -0,0 +1,118 @@
+package channels
+
+import (
+	"context"
+	"time"
+)
+
+// Batch groups the values of in into slices of up to size values. A batch
+// is emitted when it is full, or maxWait after its first value arrived,
+// whichever comes first, so a trickle of values is never held back for
+// long; maxWait 0 means batches are only cut by size. A partial batch is
+// flushed when in is closed. It panics if size is less than 1.
+func Batch[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	if size < 1 {
+		panic("channels: batch size must be at least 1")
+	}
+	out := make(chan []T)
+	clock := timeSource
+
+	go func() {
+		defer close(out)
+
+		var batch []T
+		var deadline <-chan time.Time // nil (never fires) while the batch is empty.
+		stop := func() {}
+
+		flush := func() bool {
+			stop()
+			stop, deadline = func() {}, nil
+			if len(batch) == 0 {
+				return true
+			}
+			b := batch
+			batch = nil
+			return send(ctx, out, b)
+		}
+
+		for {
+			select {
+			case v, open := <-in:
+				if !open {
+					flush()
+					return
+				}
+				batch = append(batch, v)
+				if len(batch) == 1 && maxWait > 0 {
+					deadline, stop = clock.timer(maxWait)
+				}
+				if len(batch) == size && !flush() {
+					return
+				}
+
+			case <-deadline:
+				if !flush() {
+					return
+				}
+
+			case <-ctx.Done():
+				stop()
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Window emits windows of size consecutive values of in, starting a new
+// window every step values:
+//   - step == size gives tumbling windows: [1 2 3] [4 5 6] ...
+//   - step < size gives sliding windows that overlap: [1 2 3] [2 3 4] ...
+//   - step > size skips the values in between.
+//
+// Every window is a fresh slice. Only full windows are emitted. It panics
+// if size or step is less than 1.
+func Window[T any](ctx context.Context, in <-chan T, size, step int) <-chan []T {
+	if size < 1 || step < 1 {
+		panic("channels: window size and step must be at least 1")
+	}
+	out := make(chan []T)
+
+	go func() {
+		defer close(out)
+
+		var buf []T
+		skip := 0 // Values to ignore before the next window starts (step > size).
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+
+			if skip > 0 {
+				skip--
+				continue
+			}
+			buf = append(buf, v)
+			if len(buf) < size {
+				continue
+			}
+
+			w := make([]T, size)
+			copy(w, buf)
+			if !send(ctx, out, w) {
+				return
+			}
+
+			if step < size {
+				buf = append(buf[:0], buf[step:]...)
+			} else {
+				buf = buf[:0]
+				skip = step - size
+			}
+		}
+	}()
+
+	return out
+}
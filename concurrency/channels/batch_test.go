@@ -0,0 +1,146 @@
+package channels_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// receive returns the next value of ch, failing the test if none comes
+// within a second.
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("no value within a second")
+	}
+	panic("unreachable")
+}
+
+// quiet fails the test if ch delivers anything right now.
+func quiet[T any](t *testing.T, ch <-chan T) {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		t.Fatalf("got %v (open: %v), want nothing yet", v, ok)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	got := collect(t, channels.Batch(context.Background(), numbers(0, 7), 3, 0))
+	want := [][]int{{0, 1, 2}, {3, 4, 5}, {6}} // The rest is flushed on close.
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("Batch() = %v, want %v", got, want)
+	}
+}
+
+func TestBatchMaxWait(t *testing.T) {
+	clock := channels.UseFakeClock(t)
+	in := make(chan int)
+	out := channels.Batch(context.Background(), in, 3, time.Second)
+
+	// A trickle: the first value starts the wait, the batch is cut when
+	// it is over.
+	in <- 1
+	clock.WaitCalls(t, 1)
+	in <- 2
+	clock.Advance(999 * time.Millisecond)
+	quiet(t, out)
+	clock.Advance(time.Millisecond)
+	if got := receive(t, out); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("first batch %v, want 1, 2", got)
+	}
+
+	// A full batch goes out at once, and its wait is called off.
+	for _, v := range []int{3, 4, 5} {
+		in <- v
+	}
+	if got := receive(t, out); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Errorf("second batch %v, want 3, 4, 5", got)
+	}
+	if n := clock.Pending(); n != 0 {
+		t.Errorf("%d timers left after a full batch, want 0", n)
+	}
+
+	in <- 6
+	close(in)
+	if got := collect(t, out); len(got) != 1 || !slices.Equal(got[0], []int{6}) {
+		t.Errorf("on close: %v, want [6] flushed", got)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		size, step int
+		want       [][]int
+	}{
+		{3, 3, [][]int{{1, 2, 3}, {4, 5, 6}}},                                  // Tumbling.
+		{3, 1, [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}, {4, 5, 6}, {5, 6, 7}}}, // Sliding.
+		{3, 2, [][]int{{1, 2, 3}, {3, 4, 5}, {5, 6, 7}}},                       // Sliding by 2.
+		{2, 3, [][]int{{1, 2}, {4, 5}}},                                        // Skipping 3 and 6.
+		{1, 1, [][]int{{1}, {2}, {3}, {4}, {5}, {6}, {7}}},                     // One by one.
+		{8, 1, nil}, // Never full.
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("size %d step %d", tt.size, tt.step), func(t *testing.T) {
+			got := collect(t, channels.Window(context.Background(), values(1, 2, 3, 4, 5, 6, 7), tt.size, tt.step))
+			// Comparing after the fact also checks that no window shares
+			// its array with a later one.
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("Window() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchPanics(t *testing.T) {
+	for name, f := range map[string]func(){
+		"batch size 0":  func() { channels.Batch(context.Background(), values[int](), 0, 0) },
+		"window size 0": func() { channels.Window(context.Background(), values[int](), 0, 1) },
+		"window step 0": func() { channels.Window(context.Background(), values[int](), 1, 0) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic")
+				}
+			}()
+			f()
+		})
+	}
+}
+
+func TestBatchCancelled(t *testing.T) {
+	tests := []struct {
+		name string
+		out  func(ctx context.Context, in <-chan int) <-chan []int
+	}{
+		{"batch", func(ctx context.Context, in <-chan int) <-chan []int { return channels.Batch(ctx, in, 10, time.Hour) }},
+		{"window", func(ctx context.Context, in <-chan int) <-chan []int { return channels.Window(ctx, in, 10, 1) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			in := make(chan int)
+			out := tt.out(ctx, in)
+
+			in <- 1 // A partial batch or window, dropped on cancellation.
+			cancel()
+			if got := collect(t, out); len(got) != 0 {
+				t.Errorf("got %v after cancel, want nothing", got)
+			}
+		})
+	}
+}
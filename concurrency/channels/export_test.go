@@ -0,0 +1,100 @@
+package channels
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// FakeClock is a clock whose time only moves when Advance is called.
+type FakeClock struct {
+	mu     sync.Mutex
+	t      time.Time
+	calls  int // now and timer calls so far.
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// UseFakeClock makes the functions called during the test run on a
+// FakeClock.
+func UseFakeClock(t *testing.T) *FakeClock {
+	c := &FakeClock{t: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	timeSource = c
+	t.Cleanup(func() { timeSource = realClock{} })
+	return c
+}
+
+func (c *FakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	return c.t
+}
+
+func (c *FakeClock) timer(d time.Duration) (<-chan time.Time, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+
+	ft := &fakeTimer{at: c.t.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, ft)
+	return ft.c, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.remove(ft)
+	}
+}
+
+func (c *FakeClock) remove(ft *fakeTimer) {
+	for i, t := range c.timers {
+		if t == ft {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Advance moves the clock forward by d and fires the timers now due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.t = c.t.Add(d)
+	for _, ft := range append([]*fakeTimer(nil), c.timers...) {
+		if !ft.at.After(c.t) {
+			ft.c <- c.t
+			c.remove(ft)
+		}
+	}
+}
+
+// Pending returns how many timers are set and not due yet.
+func (c *FakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// WaitCalls waits until the clock has been read or asked for a timer n
+// times in all: the goroutine under test has then handled the value that
+// made it call.
+func (c *FakeClock) WaitCalls(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.mu.Lock()
+		calls := c.calls
+		c.mu.Unlock()
+		if calls >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the clock was used %d times, want %d", calls, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,164 @@
+package channels
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+)
+
+// Tagged is a value together with the index of the input it came from.
+type Tagged[T any] struct {
+	Src int
+	V   T
+}
+
+// FairMerge is a Merge that says where each value came from and shares
+// the output fairly between its inputs.
+//
+// Merge relies on select, which picks at random among the ready inputs:
+// there is no way to give an input a larger share, and no record of who
+// got what. FairMerge holds one value per input and, whenever the consumer
+// is ready, hands out the held values in weighted round robin: with
+// weights 3, 1, 1 and every input busy, the first gets 3/5 of the output.
+// An input with nothing to send is skipped, so its share goes to the others.
+type FairMerge[T any] struct {
+	out     chan Tagged[T]
+	weights []int
+	counts  []atomic.Int64 // Values emitted per input.
+}
+
+// MergeFair starts a FairMerge of chans. weights gives each input its
+// share; nil means plain round robin, and weights below 1 count as 1.
+// It panics if weights is not nil and doesn't have one weight per input.
+//
+// Like Merge, the output is closed once every input is closed and
+// drained, or ctx is cancelled, and nil inputs are treated as closed.
+func MergeFair[T any](ctx context.Context, weights []int, chans ...<-chan T) *FairMerge[T] {
+	if weights != nil && len(weights) != len(chans) {
+		panic("channels: MergeFair needs one weight per input")
+	}
+
+	f := &FairMerge[T]{
+		out:     make(chan Tagged[T]),
+		weights: make([]int, len(chans)),
+		counts:  make([]atomic.Int64, len(chans)),
+	}
+	for i := range f.weights {
+		f.weights[i] = 1
+		if weights != nil {
+			f.weights[i] = max(weights[i], 1)
+		}
+	}
+
+	go f.run(ctx, chans)
+	return f
+}
+
+// Output returns the merged channel.
+func (f *FairMerge[T]) Output() <-chan Tagged[T] {
+	return f.out
+}
+
+// Counts returns how many values each input has had emitted so far.
+func (f *FairMerge[T]) Counts() []int64 {
+	counts := make([]int64, len(f.counts))
+	for i := range f.counts {
+		counts[i] = f.counts[i].Load()
+	}
+	return counts
+}
+
+// run is the scheduler: it fills the one-value slot of every input and
+// emits the slots in weighted round robin order.
+func (f *FairMerge[T]) run(ctx context.Context, chans []<-chan T) {
+	defer close(f.out)
+
+	n := len(chans)
+	slot := make([]T, n)
+	held := make([]bool, n) // slot[i] holds a value.
+	open := make([]bool, n)
+	holding := 0 // Number of held slots.
+	for i, ch := range chans {
+		open[i] = ch != nil
+	}
+
+	// Case 0 is the cancellation, or the default of a non-blocking poll;
+	// case i+1 is chans[i], enabled while it is open and its slot empty.
+	cases := make([]reflect.SelectCase, n+1)
+	for i := range chans {
+		cases[i+1].Dir = reflect.SelectRecv
+	}
+
+	// receive fills the empty slots of the open inputs. With 'wait' set,
+	// it first blocks until a value arrives or every input has closed.
+	// It reports false if ctx is cancelled while waiting.
+	receive := func(wait bool) bool {
+		for {
+			enabled := false
+			for i, ch := range chans {
+				cases[i+1].Chan = reflect.Value{}
+				if open[i] && !held[i] {
+					cases[i+1].Chan = reflect.ValueOf(ch)
+					enabled = true
+				}
+			}
+			if !enabled {
+				return true
+			}
+			if wait {
+				cases[0] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+			} else {
+				cases[0] = reflect.SelectCase{Dir: reflect.SelectDefault}
+			}
+
+			i, v, ok := reflect.Select(cases)
+			if i == 0 {
+				return !wait // Nothing else ready, or cancelled.
+			}
+			if !ok {
+				open[i-1] = false // The nil channel trick, as in Merge.
+				continue
+			}
+			slot[i-1], _ = v.Interface().(T)
+			held[i-1] = true
+			holding++
+			wait = false
+		}
+	}
+
+	cur, credit := 0, 0 // Weighted round robin: whose turn, and how many left.
+	for {
+		if !receive(holding == 0) {
+			return
+		}
+		if holding == 0 {
+			return // Every input is closed and drained.
+		}
+
+		// Pick the next held slot, starting with the input whose turn it is.
+		i := cur
+		for !held[i] {
+			i = (i + 1) % n
+		}
+		if i != cur {
+			cur, credit = i, 0 // Inputs with nothing held lose their turn.
+		}
+		if credit == 0 {
+			credit = f.weights[i]
+		}
+
+		select {
+		case f.out <- Tagged[T]{Src: i, V: slot[i]}:
+		case <-ctx.Done():
+			return
+		}
+
+		var zero T
+		slot[i], held[i] = zero, false
+		holding--
+		f.counts[i].Add(1)
+		if credit--; credit == 0 {
+			cur = (cur + 1) % n
+		}
+	}
+}
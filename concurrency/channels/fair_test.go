@@ -0,0 +1,94 @@
+package channels_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// backlog returns a closed channel holding n values of input src:
+// src*1000, src*1000+1, ... An input that is never empty until it is
+// drained makes the round robin deterministic.
+func backlog(src, n int) <-chan int {
+	vs := make([]int, n)
+	for i := range vs {
+		vs[i] = src*1000 + i
+	}
+	return values(vs...)
+}
+
+func TestMergeFair(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		sizes   []int   // Values per input.
+		first   int     // How many values the shares are measured over.
+		want    []int64 // Values per input among the first.
+	}{
+		{"round robin", nil, []int{100, 100, 100}, 30, []int64{10, 10, 10}},
+		{"weighted 3:1:1", []int{3, 1, 1}, []int{100, 100, 100}, 50, []int64{30, 10, 10}},
+		{"weights below 1 count as 1", []int{0, -5, 2}, []int{100, 100, 100}, 40, []int64{10, 10, 20}},
+		// Input 2 runs dry after 2 values: its share goes to the others.
+		{"drained input", []int{1, 1, 10}, []int{100, 100, 2}, 42, []int64{20, 20, 2}},
+		{"nil input", []int{3, 1, 1}, []int{100, 100, -1}, 40, []int64{30, 10, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inputs []<-chan int
+			total := 0
+			for src, n := range tt.sizes {
+				if n < 0 {
+					inputs = append(inputs, nil)
+					continue
+				}
+				inputs = append(inputs, backlog(src, n))
+				total += n
+			}
+
+			m := channels.MergeFair(context.Background(), tt.weights, inputs...)
+			got := make([]int64, len(inputs))
+			next := make([]int, len(inputs)) // The next value expected per input.
+			seen := 0
+			for v := range m.Output() {
+				if v.V != v.Src*1000+next[v.Src] {
+					t.Fatalf("got %d from input %d, want %d", v.V, v.Src, v.Src*1000+next[v.Src])
+				}
+				next[v.Src]++
+				if seen++; seen <= tt.first {
+					got[v.Src]++
+				}
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("shares of the first %d values = %v, want %v", tt.first, got, tt.want)
+			}
+			if seen != total {
+				t.Errorf("%d values, want %d", seen, total)
+			}
+			counts := m.Counts()
+			for src, n := range tt.sizes {
+				if counts[src] != int64(max(n, 0)) {
+					t.Errorf("Counts() = %v, want every value of %v", counts, tt.sizes)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestMergeFairCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	never := make(chan int) // Never sends nor closes.
+	m := channels.MergeFair(ctx, nil, never, backlog(1, 1))
+
+	if v := <-m.Output(); v.Src != 1 || v.V != 1000 {
+		t.Fatalf("got %+v, want input 1's value", v)
+	}
+	cancel()
+	if got := collect(t, m.Output()); len(got) != 0 {
+		t.Errorf("got %v after cancel, want nothing", got)
+	}
+}
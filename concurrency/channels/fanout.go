@@ -0,0 +1,184 @@
+package channels
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// send sends v on ch unless ctx is cancelled first, and reports which.
+func send[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// recv receives from ch unless ctx is cancelled first. It reports false
+// when ch is closed or ctx is cancelled.
+func recv[T any](ctx context.Context, ch <-chan T) (T, bool) {
+	select {
+	case v, open := <-ch:
+		return v, open
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	}
+}
+
+// Tee copies every value of in to both outputs. Each value is delivered
+// to both before the next one is read, so the slower reader sets the pace.
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+
+	go func() {
+		defer close(out1)
+		defer close(out2)
+
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+
+			// Send to whichever is ready first, then to the other one:
+			// nil-ing out the channel that got its copy disables its case.
+			o1, o2 := out1, out2
+			for o1 != nil || o2 != nil {
+				select {
+				case o1 <- v:
+					o1 = nil
+				case o2 <- v:
+					o2 = nil
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out1, out2
+}
+
+// SlowPolicy decides what a Broadcaster does with a subscriber that
+// isn't keeping up.
+type SlowPolicy int
+
+const (
+	// Block waits for every subscriber: the slowest one sets the pace.
+	Block SlowPolicy = iota
+
+	// Drop skips a subscriber whose buffer is full; the value is lost for
+	// it and counted in Dropped.
+	Drop
+
+	// Buffer queues values for a slow subscriber without bound, so that
+	// nobody waits and nothing is lost, at the price of memory.
+	Buffer
+)
+
+// Broadcaster delivers every value of its input to each of its subscribers.
+type Broadcaster[T any] struct {
+	outs    []chan T
+	dropped []atomic.Int64
+}
+
+// Broadcast starts delivering every value of in to n subscribers following
+// policy. buffer is the capacity of each subscriber's channel; with Drop
+// it is the slack a subscriber has before it starts losing values.
+// The subscribers' channels are closed when in is closed and everything
+// has been delivered, or ctx is cancelled.
+func Broadcast[T any](ctx context.Context, in <-chan T, n int, policy SlowPolicy, buffer int) *Broadcaster[T] {
+	b := &Broadcaster[T]{
+		outs:    make([]chan T, n),
+		dropped: make([]atomic.Int64, n),
+	}
+	for i := range b.outs {
+		b.outs[i] = make(chan T, buffer)
+	}
+
+	// With Buffer, the publisher feeds one queueing goroutine per subscriber.
+	feeds := b.outs
+	if policy == Buffer {
+		feeds = make([]chan T, n)
+		for i := range feeds {
+			feeds[i] = make(chan T)
+			go queue(ctx, feeds[i], b.outs[i])
+		}
+	}
+
+	go func() {
+		defer func() {
+			for _, ch := range feeds {
+				close(ch)
+			}
+		}()
+
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+
+			for i, ch := range feeds {
+				if policy != Drop {
+					if !send(ctx, ch, v) {
+						return
+					}
+					continue
+				}
+
+				select {
+				case ch <- v:
+				default:
+					b.dropped[i].Add(1)
+				}
+			}
+		}
+	}()
+
+	return b
+}
+
+// Subscriber returns the channel of subscriber i.
+func (b *Broadcaster[T]) Subscriber(i int) <-chan T {
+	return b.outs[i]
+}
+
+// Dropped returns how many values subscriber i has missed under Drop.
+func (b *Broadcaster[T]) Dropped(i int) int64 {
+	return b.dropped[i].Load()
+}
+
+// queue forwards in to out through an unbounded FIFO, so that sends on
+// in never wait for the reader of out. It closes out once in is closed
+// and the queue is empty, or ctx is cancelled.
+func queue[T any](ctx context.Context, in <-chan T, out chan<- T) {
+	defer close(out)
+
+	var q []T
+	for in != nil || len(q) > 0 {
+		// Only offer a value when there is one: a nil channel disables the case.
+		var next T
+		var outCh chan<- T
+		if len(q) > 0 {
+			next, outCh = q[0], out
+		}
+
+		select {
+		case v, open := <-in:
+			if !open {
+				in = nil
+				continue
+			}
+			q = append(q, v)
+		case outCh <- next:
+			var zero T
+			q[0] = zero
+			q = q[1:]
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package channels_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// collectAll drains every channel concurrently, so that a producer
+// waiting for the slowest reader can make progress.
+func collectAll[T any](t *testing.T, chans ...<-chan T) [][]T {
+	t.Helper()
+	got := make([][]T, len(chans))
+	var wg sync.WaitGroup
+	for i, ch := range chans {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timeout := time.After(time.Second)
+			for {
+				select {
+				case v, ok := <-ch:
+					if !ok {
+						return
+					}
+					got[i] = append(got[i], v)
+				case <-timeout:
+					t.Errorf("channel %d still open after %d values", i, len(got[i]))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return got
+}
+
+func TestTee(t *testing.T) {
+	out1, out2 := channels.Tee(context.Background(), numbers(0, 100))
+	got := collectAll(t, out1, out2)
+
+	for i, vs := range got {
+		if len(vs) != 100 || !slices.IsSorted(vs) {
+			t.Errorf("output %d = %v, want 0 to 99 in order", i+1, vs)
+		}
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	t.Run("block", func(t *testing.T) {
+		b := channels.Broadcast(context.Background(), numbers(0, 50), 3, channels.Block, 0)
+		for i, vs := range collectAll(t, b.Subscriber(0), b.Subscriber(1), b.Subscriber(2)) {
+			if len(vs) != 50 || !slices.IsSorted(vs) || b.Dropped(i) != 0 {
+				t.Errorf("subscriber %d got %d values, dropped %d; want all 50 in order", i, len(vs), b.Dropped(i))
+			}
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		// Nobody reads until the input is done: each subscriber keeps
+		// the first 2 values, its buffer, and loses the other 3.
+		b := channels.Broadcast(context.Background(), values(1, 2, 3, 4, 5), 2, channels.Drop, 2)
+		deadline := time.Now().Add(time.Second)
+		for b.Dropped(0) < 3 || b.Dropped(1) < 3 {
+			if time.Now().After(deadline) {
+				t.Fatalf("dropped %d and %d, want 3 each", b.Dropped(0), b.Dropped(1))
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		for i := 0; i < 2; i++ {
+			if got := collect(t, b.Subscriber(i)); !slices.Equal(got, []int{1, 2}) {
+				t.Errorf("subscriber %d got %v, want 1, 2", i, got)
+			}
+			if n := b.Dropped(i); n != 3 {
+				t.Errorf("Dropped(%d) = %d, want 3", i, n)
+			}
+		}
+	})
+
+	t.Run("buffer", func(t *testing.T) {
+		// Reading one subscriber to the end while the other waits: with
+		// Block, the broadcast would stall on the second value.
+		b := channels.Broadcast(context.Background(), numbers(0, 100), 2, channels.Buffer, 0)
+		for i := 0; i < 2; i++ {
+			got := collect(t, b.Subscriber(i))
+			if len(got) != 100 || !slices.IsSorted(got) || b.Dropped(i) != 0 {
+				t.Errorf("subscriber %d got %d values, dropped %d; want all 100 in order", i, len(got), b.Dropped(i))
+			}
+		}
+	})
+}
+
+func TestFanOutCancelled(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs func(ctx context.Context, in <-chan int) []<-chan int
+	}{
+		{"tee", func(ctx context.Context, in <-chan int) []<-chan int {
+			out1, out2 := channels.Tee(ctx, in)
+			return []<-chan int{out1, out2}
+		}},
+		{"broadcast block", broadcastOutputs(channels.Block)},
+		{"broadcast drop", broadcastOutputs(channels.Drop)},
+		{"broadcast buffer", broadcastOutputs(channels.Buffer)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			in := make(chan int)
+			outs := tt.outputs(ctx, in)
+
+			in <- 1 // Taken, but nobody reads it.
+			cancel()
+			collectAll(t, outs...)
+		})
+	}
+}
+
+// broadcastOutputs returns the subscribers of a Broadcast of 3 under policy.
+func broadcastOutputs(policy channels.SlowPolicy) func(context.Context, <-chan int) []<-chan int {
+	return func(ctx context.Context, in <-chan int) []<-chan int {
+		b := channels.Broadcast(ctx, in, 3, policy, 0)
+		return []<-chan int{b.Subscriber(0), b.Subscriber(1), b.Subscriber(2)}
+	}
+}
@@ -0,0 +1,84 @@
+// Package channels holds generic, context-aware building blocks for
+// channels, grown out of the hand-rolled helpers of the concurrency
+// examples (such as the two-input merge of nil-channel).
+//
+// Every function owns the channels it returns: it closes them exactly once,
+// when its inputs are exhausted or its context is cancelled.
+package channels
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"advanced-concepts/concurrency/tracing"
+)
+
+// Merge multiplexes any number of channels into one.
+//
+// It is the 'nil channel' pattern of the nil-channel example generalised
+// with reflect.Select: an input that closes has its select case set to a
+// nil channel, which disables it, and the output is closed once every input
+// has closed or ctx is cancelled. With no inputs, the output is closed
+// right away. nil inputs are treated as already closed.
+func Merge[T any](ctx context.Context, chans ...<-chan T) <-chan T {
+	return MergeWithTracer(ctx, nil, chans...)
+}
+
+// MergeWithTracer is Merge with a merge goroutine that traces to t when it
+// disables an input and when it closes the output, the way the nil-channel
+// example's merge did. Inputs are named ch1, ch2, ... in the order given.
+func MergeWithTracer[T any](ctx context.Context, t *tracing.Tracer, chans ...<-chan T) <-chan T {
+	out := make(chan T)
+
+	// Case 0 is the cancellation; case i+1 is chans[i].
+	cases := make([]reflect.SelectCase, len(chans)+1)
+	cases[0] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+	open := 0
+	for i, ch := range chans {
+		cases[i+1].Dir = reflect.SelectRecv
+		if ch == nil {
+			continue // Its Chan stays the zero Value: a nil channel.
+		}
+		cases[i+1].Chan = reflect.ValueOf(ch)
+		open++
+	}
+
+	trace := t.For(tracing.Indented("Merge Goroutine"))
+
+	go func() {
+		defer close(out)
+
+		for open > 0 {
+			i, v, ok := reflect.Select(cases)
+			if i == 0 {
+				trace("cancel", "Cancelled. Closing merged channel.")
+				return
+			}
+			if !ok {
+				// --- THE KEY TRICK ---
+				// The input is closed: a nil channel disables its case.
+				name := fmt.Sprintf("ch%d", i)
+				trace("nil-out", name+" closed, setting to nil.", tracing.Channel(name))
+				cases[i].Chan = reflect.Value{}
+				open--
+				continue
+			}
+
+			x, _ := v.Interface().(T) // Fails only on a nil interface value: the zero T.
+			select {
+			case out <- x:
+			case <-ctx.Done():
+				trace("cancel", "Cancelled. Closing merged channel.")
+				return
+			}
+		}
+		if len(chans) == 2 {
+			trace("close", "Both channels nil. Closing merged channel.")
+		} else {
+			trace("close", "All channels nil. Closing merged channel.")
+		}
+	}()
+
+	return out
+}
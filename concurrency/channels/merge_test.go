@@ -0,0 +1,106 @@
+package channels_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+	"advanced-concepts/concurrency/tracing"
+)
+
+// numbers returns a channel that sends from, from+1, ... to-1 from its own
+// goroutine and closes, so that inputs close at different times.
+func numbers(from, to int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := from; i < to; i++ {
+			ch <- i
+		}
+	}()
+	return ch
+}
+
+// collect drains ch, failing the test if it isn't closed within a second.
+func collect[T any](t *testing.T, ch <-chan T) []T {
+	t.Helper()
+	var vs []T
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return vs
+			}
+			vs = append(vs, v)
+		case <-timeout:
+			t.Fatalf("channel still open after %d values", len(vs))
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// 10 inputs of 0, 10, ... 90 values, so they close at different times.
+	var many []<-chan int
+	var manyWant []int
+	for i := 0; i < 10; i++ {
+		many = append(many, numbers(i*100, i*100+i*10))
+		for v := i * 100; v < i*100+i*10; v++ {
+			manyWant = append(manyWant, v)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		inputs []<-chan int
+		want   []int
+	}{
+		{"no inputs", nil, nil},
+		{"one input", []<-chan int{numbers(0, 5)}, []int{0, 1, 2, 3, 4}},
+		{"two inputs", []<-chan int{values(1, 3, 5), values(2, 4)}, []int{1, 2, 3, 4, 5}},
+		{"many inputs", many, manyWant},
+		{"nil inputs", []<-chan int{nil, values(1, 2), nil}, []int{1, 2}},
+		{"only nil inputs", []<-chan int{nil, nil}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collect(t, channels.Merge(context.Background(), tt.inputs...))
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	never := make(chan int) // Never sends nor closes.
+	merged := channels.Merge(ctx, never, nil)
+
+	cancel()
+	if got := collect(t, merged); len(got) != 0 {
+		t.Errorf("Merge() = %v after cancel, want nothing", got)
+	}
+}
+
+func TestMergeWithTracer(t *testing.T) {
+	var buf bytes.Buffer
+	collect(t, channels.MergeWithTracer(context.Background(), tracing.NewText(&buf), values(1), values[int]()))
+
+	out := buf.String()
+	for _, want := range []string{
+		"ch1 closed, setting to nil.",
+		"ch2 closed, setting to nil.",
+		"Both channels nil. Closing merged channel.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace is missing %q:\n%s", want, out)
+		}
+	}
+}
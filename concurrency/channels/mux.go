@@ -0,0 +1,218 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Why a source stopped, as reported by Source.Err.
+var (
+	ErrSourceClosed = errors.New("channels: source closed")
+	ErrDetached     = errors.New("channels: source detached")
+	ErrShutdown     = errors.New("channels: mux shut down")
+)
+
+// ErrMuxClosed is returned by Attach once the mux no longer takes sources.
+var ErrMuxClosed = errors.New("channels: mux closed")
+
+// MuxPolicy decides when a Mux closes its output.
+type MuxPolicy int
+
+const (
+	// KeepOpen closes the output only on Shutdown or cancellation,
+	// so it survives periods without sources.
+	KeepOpen MuxPolicy = iota
+
+	// CloseWhenEmpty also closes the output as soon as the last attached
+	// source is gone. A new Mux with no sources yet stays open.
+	CloseWhenEmpty
+)
+
+// Mux is a Merge whose inputs may come and go while it runs, like clients
+// of a server fanning their messages into one handler.
+//
+// Each attached source is forwarded by its own goroutine until it closes,
+// is detached, or the Mux shuts down; its Source handle reports which.
+type Mux[T any] struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	policy MuxPolicy
+	out    chan T
+
+	mu       sync.Mutex
+	nextID   int
+	active   int  // Running source goroutines.
+	attached bool // A source was attached at least once.
+	closed   bool
+	done     chan struct{}
+}
+
+// NewMux creates a Mux bound to ctx: cancelling ctx stops every source
+// and closes the output, like Shutdown.
+func NewMux[T any](ctx context.Context, policy MuxPolicy) *Mux[T] {
+	ctx, cancel := context.WithCancelCause(ctx)
+	m := &Mux[T]{
+		ctx:    ctx,
+		cancel: cancel,
+		policy: policy,
+		out:    make(chan T),
+		done:   make(chan struct{}),
+	}
+
+	// The output may have to close with no source goroutine left to do it.
+	context.AfterFunc(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.maybeClose()
+	})
+
+	return m
+}
+
+// Output returns the multiplexed channel. It is closed exactly once,
+// after every source goroutine has stopped.
+func (m *Mux[T]) Output() <-chan T {
+	return m.out
+}
+
+// Done is closed together with the output.
+func (m *Mux[T]) Done() <-chan struct{} {
+	return m.done
+}
+
+// Len returns the number of sources being forwarded.
+func (m *Mux[T]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
+}
+
+// Attach starts forwarding ch to the output. It returns ErrMuxClosed
+// once the Mux has closed or is shutting down.
+func (m *Mux[T]) Attach(ch <-chan T) (*Source, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed || m.ctx.Err() != nil {
+		return nil, ErrMuxClosed
+	}
+
+	m.nextID++
+	s := &Source{
+		id:     m.nextID,
+		detach: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	m.active++
+	m.attached = true
+
+	go m.forward(s, ch)
+	return s, nil
+}
+
+// Shutdown detaches every source and closes the output once they have
+// stopped. It is safe to call more than once.
+func (m *Mux[T]) Shutdown() {
+	m.cancel(ErrShutdown)
+}
+
+// forward runs one source and reports why it stopped.
+func (m *Mux[T]) forward(s *Source, ch <-chan T) {
+	err := m.pump(s, ch)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s.stop(err)
+	m.active--
+	m.maybeClose()
+}
+
+// pump copies ch to the output until ch closes, the source is detached
+// or the Mux is cancelled. A value received just before a detach is dropped.
+func (m *Mux[T]) pump(s *Source, ch <-chan T) error {
+	for {
+		select {
+		case v, open := <-ch:
+			if !open {
+				return ErrSourceClosed
+			}
+			select {
+			case m.out <- v:
+			case <-s.detach:
+				return ErrDetached
+			case <-m.ctx.Done():
+				return context.Cause(m.ctx)
+			}
+
+		case <-s.detach:
+			return ErrDetached
+		case <-m.ctx.Done():
+			return context.Cause(m.ctx)
+		}
+	}
+}
+
+// maybeClose closes the output if the policy says so and no source
+// goroutine can send on it any more. m.mu must be held.
+func (m *Mux[T]) maybeClose() {
+	if m.closed || m.active > 0 {
+		return
+	}
+	if m.ctx.Err() == nil && !(m.policy == CloseWhenEmpty && m.attached) {
+		return
+	}
+
+	m.closed = true
+	close(m.out)
+	close(m.done)
+	m.cancel(ErrShutdown) // Release the context; a no-op if already cancelled.
+}
+
+// Source is the handle of a channel attached to a Mux.
+type Source struct {
+	id         int
+	detach     chan struct{}
+	detachOnce sync.Once
+	done       chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// ID identifies the source within its Mux, in attach order from 1.
+func (s *Source) ID() int {
+	return s.id
+}
+
+// Detach stops forwarding the source. It does not close the source's
+// channel, which still belongs to its producer. It is safe to call
+// more than once, and after the source has stopped.
+func (s *Source) Detach() {
+	s.detachOnce.Do(func() {
+		close(s.detach)
+	})
+}
+
+// Done is closed once the source is no longer forwarded.
+func (s *Source) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns nil while the source is forwarded, and then why it stopped:
+// ErrSourceClosed, ErrDetached, ErrShutdown or the cause of the
+// cancellation of the Mux's context.
+func (s *Source) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// stop records why the source stopped and closes Done.
+func (s *Source) stop(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	close(s.done)
+}
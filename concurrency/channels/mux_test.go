@@ -0,0 +1,208 @@
+package channels_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// stopped waits for s to stop and returns why, failing the test if it is
+// still forwarded after a second.
+func stopped(t *testing.T, s *channels.Source) error {
+	t.Helper()
+	select {
+	case <-s.Done():
+		return s.Err()
+	case <-time.After(time.Second):
+		t.Fatalf("source %d still forwarded", s.ID())
+		return nil
+	}
+}
+
+// attach attaches ch to m, failing the test on error.
+func attach[T any](t *testing.T, m *channels.Mux[T], ch <-chan T) *channels.Source {
+	t.Helper()
+	s, err := m.Attach(ch)
+	if err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	return s
+}
+
+// isOpen reports whether m's output is still open a little while later.
+// Only for a Mux with no source left to send.
+func isOpen[T any](m *channels.Mux[T]) bool {
+	select {
+	case <-m.Output():
+		return false
+	case <-time.After(20 * time.Millisecond):
+		return true
+	}
+}
+
+func TestMuxPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   channels.MuxPolicy
+		wantOpen bool // Once both sources have closed.
+	}{
+		{"keep open", channels.KeepOpen, true},
+		{"close when empty", channels.CloseWhenEmpty, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := channels.NewMux[int](context.Background(), tt.policy)
+			defer m.Shutdown()
+			if !isOpen(m) {
+				t.Fatal("output closed before any source was attached")
+			}
+
+			a := attach(t, m, values(1, 2))
+			b := attach(t, m, values(3))
+			if a.ID() != 1 || b.ID() != 2 {
+				t.Errorf("IDs %d and %d, want 1 and 2", a.ID(), b.ID())
+			}
+			var got []int
+			for len(got) < 3 {
+				got = append(got, <-m.Output())
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, []int{1, 2, 3}) {
+				t.Errorf("output %v, want 1, 2, 3", got)
+			}
+
+			for _, s := range []*channels.Source{a, b} {
+				if err := stopped(t, s); !errors.Is(err, channels.ErrSourceClosed) {
+					t.Errorf("source %d: Err() = %v, want ErrSourceClosed", s.ID(), err)
+				}
+			}
+			if open := isOpen(m); open != tt.wantOpen {
+				t.Fatalf("output open = %v with no sources left, want %v", open, tt.wantOpen)
+			}
+			if !tt.wantOpen {
+				if _, err := m.Attach(values(4)); !errors.Is(err, channels.ErrMuxClosed) {
+					t.Errorf("Attach after close: %v, want ErrMuxClosed", err)
+				}
+				return
+			}
+
+			// A kept-open Mux takes new sources after a lull.
+			attach(t, m, values(4))
+			if v := <-m.Output(); v != 4 {
+				t.Errorf("got %d from the late source, want 4", v)
+			}
+		})
+	}
+}
+
+func TestMuxDetach(t *testing.T) {
+	m := channels.NewMux[int](context.Background(), channels.CloseWhenEmpty)
+	never := make(chan int) // Never sends nor closes.
+	idle := attach(t, m, never)
+	busy := attach(t, m, numbers(0, 3))
+
+	idle.Detach()
+	idle.Detach() // A no-op.
+	if err := stopped(t, idle); !errors.Is(err, channels.ErrDetached) {
+		t.Errorf("Err() = %v, want ErrDetached", err)
+	}
+
+	// The other source goes on, and the output closes after it.
+	if got := collect(t, m.Output()); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("output %v, want 0, 1, 2", got)
+	}
+	if err := stopped(t, busy); !errors.Is(err, channels.ErrSourceClosed) {
+		t.Errorf("Err() = %v, want ErrSourceClosed", err)
+	}
+	if n := m.Len(); n != 0 {
+		t.Errorf("Len() = %d, want 0", n)
+	}
+	busy.Detach() // After the source stopped: a no-op as well.
+}
+
+func TestMuxShutdown(t *testing.T) {
+	m := channels.NewMux[int](context.Background(), channels.KeepOpen)
+	s := attach(t, m, make(chan int))
+	if n := m.Len(); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+
+	m.Shutdown()
+	m.Shutdown() // Safe to repeat.
+	if got := collect(t, m.Output()); len(got) != 0 {
+		t.Errorf("output %v, want nothing", got)
+	}
+	<-m.Done()
+	if err := stopped(t, s); !errors.Is(err, channels.ErrShutdown) {
+		t.Errorf("Err() = %v, want ErrShutdown", err)
+	}
+	if _, err := m.Attach(values(1)); !errors.Is(err, channels.ErrMuxClosed) {
+		t.Errorf("Attach after Shutdown: %v, want ErrMuxClosed", err)
+	}
+}
+
+func TestMuxCancelled(t *testing.T) {
+	errGone := errors.New("client gone")
+
+	t.Run("with a source", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		m := channels.NewMux[int](ctx, channels.KeepOpen)
+		s := attach(t, m, make(chan int))
+
+		cancel(errGone)
+		collect(t, m.Output())
+		if err := stopped(t, s); !errors.Is(err, errGone) {
+			t.Errorf("Err() = %v, want the cause of the cancellation", err)
+		}
+	})
+
+	t.Run("without sources", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		m := channels.NewMux[int](ctx, channels.KeepOpen)
+
+		cancel(errGone)
+		collect(t, m.Output())
+		if _, err := m.Attach(values(1)); !errors.Is(err, channels.ErrMuxClosed) {
+			t.Errorf("Attach after cancellation: %v, want ErrMuxClosed", err)
+		}
+	})
+}
+
+func TestMuxClosesOnce(t *testing.T) {
+	// Sources closing, detaching and a Shutdown all race to close the
+	// output: a second close would panic.
+	for i := 0; i < 100; i++ {
+		m := channels.NewMux[int](context.Background(), channels.CloseWhenEmpty)
+		var sources []*channels.Source
+		for j := 0; j < 5; j++ {
+			sources = append(sources, attach(t, m, numbers(0, j)))
+		}
+
+		var wg sync.WaitGroup
+		for _, s := range sources[:2] {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.Detach()
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Shutdown()
+		}()
+
+		collect(t, m.Output())
+		wg.Wait()
+		for _, s := range sources {
+			stopped(t, s)
+		}
+		<-m.Done()
+	}
+}
@@ -0,0 +1,107 @@
+package channels
+
+import (
+	"context"
+	"time"
+)
+
+// clock is the time source of Batch, Debounce and Throttle, so that tests
+// can move time themselves.
+type clock interface {
+	now() time.Time
+
+	// timer returns a channel that receives once d has elapsed, and a
+	// function that stops the timer.
+	timer(d time.Duration) (<-chan time.Time, func())
+}
+
+// realClock is the clock backed by the time package.
+type realClock struct{}
+
+func (realClock) now() time.Time { return time.Now() }
+
+func (realClock) timer(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTimer(d)
+	return t.C, func() { t.Stop() }
+}
+
+// timeSource is the clock of the functions called from now on.
+var timeSource clock = realClock{}
+
+// Debounce emits a value only once in has been quiet for 'quiet': a burst
+// of values collapses into its last one, like a search box waiting for the
+// user to stop typing. The pending value is flushed when in is closed.
+func Debounce[T any](ctx context.Context, in <-chan T, quiet time.Duration) <-chan T {
+	out := make(chan T)
+	clock := timeSource
+
+	go func() {
+		defer close(out)
+
+		var quietDone <-chan time.Time // nil (never fires) while nothing is pending.
+		stop := func() {}
+		defer func() { stop() }()
+
+		var last T
+		for {
+			select {
+			case v, open := <-in:
+				if !open {
+					if quietDone != nil {
+						send(ctx, out, last)
+					}
+					return
+				}
+				last = v
+				stop() // Every value restarts the quiet period.
+				quietDone, stop = clock.timer(quiet)
+
+			case <-quietDone:
+				quietDone, stop = nil, func() {}
+				if !send(ctx, out, last) {
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Throttle emits at most one value per 'every': the first value goes
+// through, and those arriving before 'every' has elapsed are dropped.
+// To slow values down without losing any, use a pool.TokenBucket instead.
+func Throttle[T any](ctx context.Context, in <-chan T, every time.Duration) <-chan T {
+	out := make(chan T)
+	clock := timeSource
+
+	go func() {
+		defer close(out)
+
+		var next time.Time // When the next value may go through.
+		for {
+			select {
+			case v, open := <-in:
+				if !open {
+					return
+				}
+				now := clock.now()
+				if now.Before(next) {
+					continue // Too soon: dropped.
+				}
+				next = now.Add(every)
+				if !send(ctx, out, v) {
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,92 @@
+package channels_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+func TestDebounce(t *testing.T) {
+	clock := channels.UseFakeClock(t)
+	in := make(chan string)
+	out := channels.Debounce(context.Background(), in, time.Second)
+
+	// A burst: every value restarts the quiet period.
+	for i, v := range []string{"g", "go", "gop"} {
+		in <- v
+		clock.WaitCalls(t, i+1)
+		clock.Advance(999 * time.Millisecond)
+	}
+	quiet(t, out)
+	clock.Advance(time.Millisecond)
+	if got := receive(t, out); got != "gop" {
+		t.Errorf("after the burst: %q, want the last value, gop", got)
+	}
+	quiet(t, out)
+
+	// The pending value is flushed on close, without waiting.
+	in <- "gopher"
+	clock.WaitCalls(t, 4)
+	close(in)
+	if got := collect(t, out); !slices.Equal(got, []string{"gopher"}) {
+		t.Errorf("on close: %q, want gopher", got)
+	}
+	if n := clock.Pending(); n != 0 {
+		t.Errorf("%d timers left, want 0", n)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	clock := channels.UseFakeClock(t)
+	in := make(chan int)
+	out := channels.Throttle(context.Background(), in, time.Second)
+
+	in <- 1
+	if got := receive(t, out); got != 1 {
+		t.Errorf("got %d, want the first value, 1", got)
+	}
+
+	// Too soon: 2 and 3 are dropped, 4 is a second after 1.
+	in <- 2
+	clock.Advance(500 * time.Millisecond)
+	in <- 3
+	clock.WaitCalls(t, 3)
+	clock.Advance(500 * time.Millisecond)
+	in <- 4
+	if got := receive(t, out); got != 4 {
+		t.Errorf("got %d, want 4, the first value a second later", got)
+	}
+
+	in <- 5
+	close(in)
+	if got := collect(t, out); len(got) != 0 {
+		t.Errorf("got %v, want 5 dropped and the output closed", got)
+	}
+}
+
+func TestRateCancelled(t *testing.T) {
+	tests := []struct {
+		name string
+		out  func(ctx context.Context, in <-chan int) <-chan int
+	}{
+		{"debounce", func(ctx context.Context, in <-chan int) <-chan int { return channels.Debounce(ctx, in, time.Hour) }},
+		{"throttle", func(ctx context.Context, in <-chan int) <-chan int { return channels.Throttle(ctx, in, time.Hour) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			in := make(chan int)
+			out := tt.out(ctx, in)
+
+			in <- 1 // Pending, or waiting for a reader.
+			cancel()
+			if got := collect(t, out); len(got) != 0 {
+				t.Errorf("got %v after cancel, want nothing", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package channels
+
+import (
+	"container/heap"
+	"context"
+)
+
+// MergeSorted merges channels that each deliver their values in order
+// into one channel that delivers all of them in order, like the merge
+// step of a merge sort over streams (timestamps, offsets, ...).
+//
+// cmp returns a negative number when a sorts before b, zero when they are
+// equal and a positive number otherwise, as for slices.SortFunc. Equal
+// values keep the order of their inputs: the first channel's come first.
+//
+// A value can only be emitted once every open input has a value in hand,
+// since any of them may still send a smaller one: a slow input holds the
+// output back, and an input that never sends and never closes blocks it
+// until ctx is cancelled. Inputs may close at any time, including before
+// sending anything. The output is closed once every input is closed and
+// drained, or ctx is cancelled.
+func MergeSorted[T any](ctx context.Context, cmp func(a, b T) int, chans ...<-chan T) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		h := &heads[T]{cmp: cmp}
+
+		// Prime the heap with the first value of every input.
+		for i, ch := range chans {
+			if !h.pull(ctx, ch, i) {
+				return
+			}
+		}
+
+		for h.Len() > 0 {
+			top := h.items[0]
+			select {
+			case out <- top.v:
+			case <-ctx.Done():
+				return
+			}
+
+			// Replace the emitted value with the next one of the same input.
+			heap.Pop(h)
+			if !h.pull(ctx, chans[top.src], top.src) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// head is the smallest value not yet emitted of one input.
+type head[T any] struct {
+	v   T
+	src int // Index of the input.
+}
+
+// heads is a min-heap of the inputs' heads, ordered by cmp and then by
+// input index, which keeps the merge stable.
+type heads[T any] struct {
+	items []head[T]
+	cmp   func(a, b T) int
+}
+
+func (h *heads[T]) Len() int      { return len(h.items) }
+func (h *heads[T]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *heads[T]) Push(x any)    { h.items = append(h.items, x.(head[T])) }
+
+func (h *heads[T]) Less(i, j int) bool {
+	if c := h.cmp(h.items[i].v, h.items[j].v); c != 0 {
+		return c < 0
+	}
+	return h.items[i].src < h.items[j].src
+}
+
+func (h *heads[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items[len(h.items)-1] = head[T]{} // Don't keep the value alive.
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// pull waits for the next value of input 'src' and pushes it on the heap;
+// a closed input simply drops out. It reports false if ctx is cancelled.
+func (h *heads[T]) pull(ctx context.Context, ch <-chan T, src int) bool {
+	if ch == nil {
+		return true // Like Merge: a nil input is an empty one.
+	}
+
+	select {
+	case v, open := <-ch:
+		if open {
+			heap.Push(h, head[T]{v: v, src: src})
+		}
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,94 @@
+package channels_test
+
+import (
+	"cmp"
+	"context"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// record is a value with the input it came from, to check stability.
+type record struct {
+	key, src int
+}
+
+func byKey(a, b record) int { return cmp.Compare(a.key, b.key) }
+
+// produce sends vs in order, yielding the processor up to 3 times before
+// each send, so that inputs interleave and close at different times
+// without slowing the test down.
+func produce(r *rand.Rand, vs []record) <-chan record {
+	yields := make([]int, len(vs))
+	for i := range yields {
+		yields[i] = r.IntN(4)
+	}
+
+	ch := make(chan record)
+	go func() {
+		defer close(ch)
+		for i, v := range vs {
+			for range yields[i] {
+				runtime.Gosched()
+			}
+			ch <- v
+		}
+	}()
+	return ch
+}
+
+// randomInputs returns between 0 and 8 sorted slices of 0 to 30 keys
+// each, drawn from a small range so that duplicates are common.
+func randomInputs(r *rand.Rand) [][]record {
+	inputs := make([][]record, r.IntN(9))
+	for src := range inputs {
+		vs := make([]record, r.IntN(31))
+		for i := range vs {
+			vs[i] = record{key: r.IntN(20), src: src}
+		}
+		slices.SortFunc(vs, byKey)
+		inputs[src] = vs
+	}
+	return inputs
+}
+
+// TestMergeSortedProperty checks that MergeSorted(inputs) equals a stable
+// sort of their concatenation: same values, same order, ties in input
+// order. The seed is fixed so that a failure can be replayed.
+func TestMergeSortedProperty(t *testing.T) {
+	const trials = 300
+	r := rand.New(rand.NewPCG(1, 2))
+
+	for i := 0; i < trials; i++ {
+		inputs := randomInputs(r)
+
+		var chans []<-chan record
+		var want []record
+		for _, vs := range inputs {
+			chans = append(chans, produce(r, vs))
+			want = append(want, vs...)
+		}
+		slices.SortStableFunc(want, byKey)
+
+		got := collect(t, channels.MergeSorted(context.Background(), byKey, chans...))
+		if !slices.Equal(got, want) {
+			t.Fatalf("trial %d, inputs %v:\n got  %v\n want %v", i, inputs, got, want)
+		}
+	}
+}
+
+func TestMergeSortedCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	// The silent input holds every value back until ctx is cancelled.
+	sent := values(record{1, 0}, record{4, 0}, record{9, 0})
+	silent := make(chan record)
+	if got := collect(t, channels.MergeSorted(ctx, byKey, sent, silent)); len(got) != 0 {
+		t.Errorf("MergeSorted() = %v, want nothing while an input is silent", got)
+	}
+}
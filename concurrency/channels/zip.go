@@ -0,0 +1,57 @@
+package channels
+
+import "context"
+
+// Pair is a value of each input of Zip.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Zip pairs the values of a and b in order: the first of a with the first
+// of b, and so on. The output is closed as soon as either input is closed
+// (a value of the other input waiting for its partner is dropped),
+// or ctx is cancelled. A nil input counts as closed, as in Merge, so
+// Zip with a nil input closes its output right away.
+func Zip[A, B any](ctx context.Context, a <-chan A, b <-chan B) <-chan Pair[A, B] {
+	out := make(chan Pair[A, B])
+
+	go func() {
+		defer close(out)
+
+		// A nil channel is never ready: below, it would look like
+		// a value already received, and every pair would be half empty.
+		if a == nil || b == nil {
+			return
+		}
+
+		for {
+			// Receive one of each, in whatever order they arrive:
+			// the channel that delivered is set to nil until the pair is sent.
+			var p Pair[A, B]
+			ca, cb := a, b
+			for ca != nil || cb != nil {
+				select {
+				case v, open := <-ca:
+					if !open {
+						return
+					}
+					p.First, ca = v, nil
+				case v, open := <-cb:
+					if !open {
+						return
+					}
+					p.Second, cb = v, nil
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if !send(ctx, out, p) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
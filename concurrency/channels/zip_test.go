@@ -0,0 +1,49 @@
+package channels_test
+
+import (
+	"context"
+	"testing"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// values returns a closed channel holding vs.
+func values[T any](vs ...T) <-chan T {
+	ch := make(chan T, len(vs))
+	for _, v := range vs {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+func TestZip(t *testing.T) {
+	tests := []struct {
+		name string
+		a    <-chan int
+		b    <-chan string
+		want []channels.Pair[int, string]
+	}{
+		{"same length", values(1, 2), values("a", "b"), []channels.Pair[int, string]{{1, "a"}, {2, "b"}}},
+		{"shorter first", values(1), values("a", "b"), []channels.Pair[int, string]{{1, "a"}}},
+		{"shorter second", values(1, 2, 3), values("a"), []channels.Pair[int, string]{{1, "a"}}},
+		{"nil first", nil, values("a", "b"), nil},
+		{"nil second", values(1, 2), nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []channels.Pair[int, string]
+			for p := range channels.Zip(context.Background(), tt.a, tt.b) {
+				got = append(got, p)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("pair %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// --- Skewed Producers ---
+
+// producer sends its own index as fast as 'every' allows
+// (0 means as fast as it can) until ctx is cancelled.
+func producer(ctx context.Context, src int, every time.Duration) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case ch <- src:
+			case <-ctx.Done():
+				return
+			}
+			if every > 0 {
+				time.Sleep(every)
+			}
+		}
+	}()
+	return ch
+}
+
+// rates: two producers that always have something to send, and a
+// trickle that can't use a fair share even if it gets one.
+var rates = []time.Duration{0, 0, 10 * time.Millisecond}
+
+// consume is the bottleneck the producers compete for.
+func consume() {
+	time.Sleep(100 * time.Microsecond)
+}
+
+const runFor = 300 * time.Millisecond
+
+// --- The Contenders ---
+
+// plain runs the random select of Merge and counts the sources itself.
+func plain() []int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), runFor)
+	defer cancel()
+
+	var inputs []<-chan int
+	for src, every := range rates {
+		inputs = append(inputs, producer(ctx, src, every))
+	}
+	counts := make([]int64, len(rates))
+	for src := range channels.Merge(ctx, inputs...) {
+		counts[src]++
+		consume()
+	}
+	return counts
+}
+
+// fair runs a FairMerge with the given weights and reads its counters.
+func fair(weights []int) []int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), runFor)
+	defer cancel()
+
+	var inputs []<-chan int
+	for src, every := range rates {
+		inputs = append(inputs, producer(ctx, src, every))
+	}
+	m := channels.MergeFair(ctx, weights, inputs...)
+	for v := range m.Output() {
+		if v.Src != v.V {
+			panic("value tagged with the wrong source")
+		}
+		consume()
+	}
+	return m.Counts()
+}
+
+// printShares prints each source's share of the output.
+func printShares(name string, counts []int64) {
+	var total int64
+	for _, c := range counts {
+		total += c
+	}
+	var shares []string
+	for _, c := range counts {
+		shares = append(shares, fmt.Sprintf("%5.1f%%", 100*float64(c)/float64(max(total, 1))))
+	}
+	fmt.Printf("   %-22s %6d  %s\n", name, total, strings.Join(shares, "  "))
+}
+
+// --- Main Function ---
+
+// main shows one run of each; TestMergeFair in concurrency/channels checks
+// the shares exactly, with inputs that are never empty.
+func main() {
+	fmt.Printf("Sources 0 and 1 send flat out, source 2 once every %v;\n", rates[2])
+	fmt.Printf("the consumer sleeps %v per value, for %v.\n\n", 100*time.Microsecond, runFor)
+	fmt.Printf("   %-22s %6s  %6s  %6s  %6s\n", "merge", "values", "src 0", "src 1", "src 2")
+
+	printShares("Merge (random select)", plain())
+	printShares("MergeFair 3:1:1", fair([]int{3, 1, 1}))
+
+	fmt.Println("\nSource 2 never gets more than it sends: its unused share")
+	fmt.Println("goes to the others, in proportion to their weights.")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// --- Clients ---
+
+// client sends 'messages' messages 'every' apart and then disconnects by
+// closing its channel. With 'messages' at 0 it never disconnects by itself:
+// it talks until ctx is cancelled, so the server has to kick it out.
+func client(ctx context.Context, name string, every time.Duration, messages int) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for i := 1; messages == 0 || i <= messages; i++ {
+			select {
+			case ch <- fmt.Sprintf("%s #%d", name, i):
+			case <-ctx.Done():
+				return
+			}
+			time.Sleep(every)
+		}
+	}()
+	return ch
+}
+
+// --- The Server ---
+
+// connect attaches a client and reports, in the background, when it goes away.
+func connect(m *channels.Mux[string], name string, ch <-chan string, wg *sync.WaitGroup) *channels.Source {
+	s, err := m.Attach(ch)
+	if err != nil {
+		fmt.Printf("   [Server]: %s refused: %v\n", name, err)
+		return nil
+	}
+	fmt.Printf("   [Server]: %s attached as source %d (%d connected)\n", name, s.ID(), m.Len())
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-s.Done()
+		fmt.Printf("   [Server]: %s (source %d) gone: %v\n", name, s.ID(), s.Err())
+	}()
+	return s
+}
+
+// serve prints every message until the output of the mux is closed.
+func serve(m *channels.Mux[string]) int {
+	n := 0
+	for msg := range m.Output() {
+		fmt.Printf("   [Handler]: %s\n", msg)
+		n++
+	}
+	return n
+}
+
+// --- Main Function to Run the Server ---
+
+func main() {
+	before := runtime.NumGoroutine()
+	ctx, disconnectAll := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	fmt.Println("1. CloseWhenEmpty: the output closes when the last client leaves")
+	m := channels.NewMux[string](ctx, channels.CloseWhenEmpty)
+	connect(m, "alice", client(ctx, "alice", 20*time.Millisecond, 3), &wg)
+	go func() {
+		// Bob joins while alice is still talking.
+		time.Sleep(30 * time.Millisecond)
+		connect(m, "bob", client(ctx, "bob", 20*time.Millisecond, 2), &wg)
+	}()
+	fmt.Printf("   [Main]: %d messages handled\n", serve(m))
+	wg.Wait()
+
+	// Joining after the close is refused.
+	connect(m, "carol", client(ctx, "carol", 0, 1), &wg)
+
+	fmt.Println("\n2. KeepOpen: a kicked client, an idle period, then Shutdown")
+	m = channels.NewMux[string](ctx, channels.KeepOpen)
+	daveCtx, daveQuits := context.WithCancel(ctx)
+	defer daveQuits()
+
+	go func() {
+		dave := connect(m, "dave", client(daveCtx, "dave", 15*time.Millisecond, 0), &wg)
+		time.Sleep(50 * time.Millisecond)
+		dave.Detach() // Kicked: the mux stops reading; the channel still belongs to the client.
+		daveQuits()
+
+		time.Sleep(50 * time.Millisecond) // Nobody connected: the output stays open.
+		connect(m, "erin", client(ctx, "erin", 10*time.Millisecond, 2), &wg)
+		connect(m, "frank", client(ctx, "frank", 40*time.Millisecond, 0), &wg)
+
+		time.Sleep(60 * time.Millisecond)
+		fmt.Println("   [Main]: shutting down")
+		m.Shutdown()
+	}()
+	fmt.Printf("   [Main]: %d messages handled\n", serve(m))
+	wg.Wait()
+
+	// The clients that are still talking (frank) or were refused (carol)
+	// are the producers' business, not the mux's: stop them too.
+	disconnectAll()
+	time.Sleep(50 * time.Millisecond)
+	fmt.Printf("\nGoroutines before: %d, after: %d\n", before, runtime.NumGoroutine())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"advanced-concepts/concurrency/lifecycle"
+	"advanced-concepts/concurrency/tracing"
+)
+
+// --- A Watcher as a Component ---
+
+// watcher is the stop-go-routine watcher reduced to its life cycle:
+// a goroutine that works until 'quit' and then cleans up.
+type watcher struct {
+	cleanup time.Duration
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+// component wraps a new watcher into a lifecycle.Component.
+func component(name string, cleanup time.Duration, dependsOn ...string) lifecycle.Component {
+	w := &watcher{cleanup: cleanup}
+	return lifecycle.Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			w.quit = make(chan struct{})
+			w.done = make(chan struct{})
+			go func() {
+				defer close(w.done)
+				<-w.quit              // ...doing work (watching)...
+				time.Sleep(w.cleanup) // Cleaning up resources.
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			close(w.quit)
+			select {
+			case <-w.done:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("cleanup abandoned: %w", ctx.Err())
+			}
+		},
+	}
+}
+
+// signalSelf sends sig to this very process after d, as an operator
+// pressing Ctrl-C (SIGINT) or an orchestrator stopping us (SIGTERM) would.
+func signalSelf(d time.Duration, sig syscall.Signal) {
+	time.AfterFunc(d, func() {
+		syscall.Kill(os.Getpid(), sig)
+	})
+}
+
+// printReport prints the stop durations of a Run.
+func printReport(r *lifecycle.Report, err error) {
+	if r != nil {
+		fmt.Printf("   signal: %v\n", r.Signal)
+		for _, s := range r.Stops {
+			fmt.Printf("   stopped %-6s in %6v  err=%v\n", s.Name, s.Duration.Round(time.Millisecond), s.Err)
+		}
+	}
+	fmt.Printf("   Run: %v\n", err)
+}
+
+// newManager registers the components of a small service. They are
+// registered out of order on purpose: the dependencies decide.
+func newManager(cfg lifecycle.Config, dbCleanup time.Duration) *lifecycle.Manager {
+	m := lifecycle.New(cfg)
+	m.Register(component("api", 20*time.Millisecond, "cache", "db"))
+	m.Register(component("cache", 50*time.Millisecond, "db"))
+	m.Register(component("db", dbCleanup))
+	return m
+}
+
+// --- Main Function to Run the Service ---
+
+func main() {
+	interactive := flag.Bool("interactive", false, "wait for a real Ctrl-C instead of signalling ourselves")
+	format := flag.String("trace", "text", "lifecycle events: text, json or off")
+	flag.Parse()
+
+	t, err := tracing.ForFormat(*format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *interactive {
+		fmt.Println("Running; press Ctrl-C to stop, twice to force.")
+		m := newManager(lifecycle.Config{Trace: t, StopTimeout: 10 * time.Second}, 3*time.Second)
+		printReport(m.Run(context.Background()))
+		return
+	}
+
+	// The stop order, the StopTimeout, the forced exit on a second signal
+	// and the refusal of dependency cycles are checked by the tests of
+	// concurrency/lifecycle.
+	fmt.Println("SIGTERM: stop in reverse dependency order, with durations")
+	signalSelf(200*time.Millisecond, syscall.SIGTERM)
+	printReport(newManager(lifecycle.Config{Trace: t}, 100*time.Millisecond).Run(context.Background()))
+}
@@ -0,0 +1,259 @@
+// Package health tells whether background workers are alive and making
+// progress.
+//
+// Every worker registers a Heartbeat and beats once per iteration of its
+// loop. A worker that hasn't beaten within its stall threshold is stalled:
+// its goroutine may still exist, but it is stuck (a hung DB call, a
+// deadlock). A worker whose period varies, such as a cron job, moves its
+// threshold along with Expect, and one that is idle on purpose Pauses.
+// A Registry reports this as liveness and readiness JSON over HTTP, the
+// way an orchestrator's probes expect it.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"advanced-concepts/concurrency/tracing"
+)
+
+// Heartbeat is one worker's progress record. Its methods are cheap and
+// safe to call from the worker's goroutine while the Registry reads it.
+// Like a nil *tracing.Tracer, a nil *Heartbeat is valid and does nothing.
+type Heartbeat struct {
+	name string
+	now  func() time.Time // The Registry's clock.
+
+	stallAfter atomic.Int64 // A time.Duration.
+	last       atomic.Int64 // UnixNano of the last beat.
+	beats      atomic.Uint64
+	ready      atomic.Bool
+	paused     atomic.Bool
+	stopped    atomic.Bool
+}
+
+// Beat records one iteration of the worker's loop.
+func (h *Heartbeat) Beat() {
+	if h == nil {
+		return
+	}
+	h.last.Store(h.now().UnixNano())
+	h.beats.Add(1)
+}
+
+// Expect sets the stall threshold: from now on, the worker is stalled when
+// it goes longer than d without beating. Workers whose next beat isn't
+// always equally far away, such as a cron job, call it after every beat.
+func (h *Heartbeat) Expect(d time.Duration) {
+	if h == nil {
+		return
+	}
+	h.stallAfter.Store(int64(d))
+}
+
+// Pause records that the worker stopped beating on purpose, e.g. a paused
+// job: it is not stalled, however long it goes without a beat, until Resume.
+func (h *Heartbeat) Pause() {
+	if h == nil {
+		return
+	}
+	h.paused.Store(true)
+}
+
+// Resume ends a Pause. It counts as a beat, so that the time spent
+// paused isn't held against the worker.
+func (h *Heartbeat) Resume() {
+	if h == nil {
+		return
+	}
+	h.Beat()
+	h.paused.Store(false)
+}
+
+// SetReady tells whether the worker can take work: typically true once
+// it has started, and false again when it begins to shut down.
+func (h *Heartbeat) SetReady(ready bool) {
+	if h == nil {
+		return
+	}
+	h.ready.Store(ready)
+}
+
+// Stop records that the worker has exited on purpose: it is no longer
+// alive, but it isn't stalled either.
+func (h *Heartbeat) Stop() {
+	if h == nil {
+		return
+	}
+	h.ready.Store(false)
+	h.stopped.Store(true)
+}
+
+// Status is the health of one worker.
+type Status struct {
+	Name      string        `json:"name"`
+	Alive     bool          `json:"alive"`   // Running and not stalled.
+	Ready     bool          `json:"ready"`   // Alive and ready for work.
+	Stalled   bool          `json:"stalled"` // No beat within the threshold.
+	Paused    bool          `json:"paused"`
+	Stopped   bool          `json:"stopped"`
+	Beats     uint64        `json:"beats"`
+	LastBeat  time.Time     `json:"last_beat"`
+	SinceBeat time.Duration `json:"since_beat_ns"`
+	Threshold time.Duration `json:"threshold_ns"`
+}
+
+// status computes the health of h at 'now'.
+func (h *Heartbeat) status(now time.Time) Status {
+	last := time.Unix(0, h.last.Load())
+	s := Status{
+		Name:      h.name,
+		Paused:    h.paused.Load(),
+		Stopped:   h.stopped.Load(),
+		Beats:     h.beats.Load(),
+		LastBeat:  last,
+		SinceBeat: now.Sub(last),
+		Threshold: time.Duration(h.stallAfter.Load()),
+	}
+	s.Stalled = !s.Stopped && !s.Paused && s.SinceBeat > s.Threshold
+	s.Alive = !s.Stopped && !s.Stalled
+	s.Ready = s.Alive && h.ready.Load()
+	return s
+}
+
+// Registry holds the heartbeats of all workers.
+type Registry struct {
+	trace func(event, msg string, attrs ...slog.Attr)
+	now   func() time.Time // time.Now, but for tests.
+
+	mu         sync.Mutex
+	heartbeats []*Heartbeat
+	byName     map[string]*Heartbeat
+}
+
+// NewRegistry returns an empty Registry. t receives the stall detector's
+// events and may be nil.
+func NewRegistry(t *tracing.Tracer) *Registry {
+	return &Registry{
+		trace:  t.For(tracing.Indented("Health")),
+		now:    time.Now,
+		byName: make(map[string]*Heartbeat),
+	}
+}
+
+// Register adds a worker that counts as stalled when it hasn't beaten for
+// longer than stallAfter, which should be a few times its loop period.
+// Registering counts as a first beat. Registering a name again replaces
+// the old heartbeat, as for a restarted worker. On a nil Registry it
+// returns a nil Heartbeat.
+func (r *Registry) Register(name string, stallAfter time.Duration) *Heartbeat {
+	if r == nil {
+		return nil
+	}
+
+	h := &Heartbeat{name: name, now: r.now}
+	h.Expect(stallAfter)
+	h.Beat()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if old, ok := r.byName[name]; ok {
+		for i := range r.heartbeats {
+			if r.heartbeats[i] == old {
+				r.heartbeats[i] = h
+			}
+		}
+	} else {
+		r.heartbeats = append(r.heartbeats, h)
+	}
+	r.byName[name] = h
+	return h
+}
+
+// Check returns the health of every worker, in registration order.
+func (r *Registry) Check() []Status {
+	r.mu.Lock()
+	heartbeats := append([]*Heartbeat(nil), r.heartbeats...)
+	r.mu.Unlock()
+
+	now := r.now()
+	status := make([]Status, len(heartbeats))
+	for i, h := range heartbeats {
+		status[i] = h.status(now)
+	}
+	return status
+}
+
+// Detect checks the workers every 'every' until quit is closed, and
+// traces each worker that becomes stalled and each that recovers.
+// onStall, if not nil, is called for every newly stalled worker.
+// It blocks: run it in its own goroutine.
+func (r *Registry) Detect(quit <-chan struct{}, every time.Duration, onStall func(Status)) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	stalled := make(map[string]bool)
+	for {
+		select {
+		case <-ticker.C:
+			for _, s := range r.Check() {
+				switch {
+				case s.Stalled && !stalled[s.Name]:
+					r.trace("stall", fmt.Sprintf("%s stalled: no beat for %v", s.Name, s.SinceBeat.Round(time.Millisecond)),
+						slog.String("worker", s.Name), slog.Duration("since", s.SinceBeat))
+					if onStall != nil {
+						onStall(s)
+					}
+				case !s.Stalled && !s.Stopped && !s.Paused && stalled[s.Name]:
+					r.trace("recover", s.Name+" is beating again", slog.String("worker", s.Name))
+				}
+				stalled[s.Name] = s.Stalled
+			}
+
+		case <-quit:
+			return
+		}
+	}
+}
+
+// report is the JSON body of the endpoints.
+type report struct {
+	Status  string   `json:"status"` // "ok" or "fail".
+	Workers []Status `json:"workers"`
+}
+
+// Handler serves the health of the workers as JSON:
+//   - /livez answers 200 if no worker is stalled, 503 otherwise;
+//   - /readyz answers 200 if every worker is ready, 503 otherwise.
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, _ *http.Request) {
+		r.respond(w, func(s Status) bool { return !s.Stalled })
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		r.respond(w, func(s Status) bool { return s.Ready })
+	})
+	return mux
+}
+
+// respond writes the report, failing if any worker fails 'ok'.
+func (r *Registry) respond(w http.ResponseWriter, ok func(Status) bool) {
+	rep := report{Status: "ok", Workers: r.Check()}
+	code := http.StatusOK
+	for _, s := range rep.Workers {
+		if !ok(s) {
+			rep.Status, code = "fail", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(rep)
+}
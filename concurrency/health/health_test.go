@@ -0,0 +1,287 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/tracing"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// newTestRegistry returns a Registry on a fake clock, tracing to t if
+// not nil.
+func newTestRegistry(t *tracing.Tracer) (*Registry, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	r := NewRegistry(t)
+	r.now = clock.now
+	return r, clock
+}
+
+// check returns the only Status of r.
+func check(t *testing.T, r *Registry) Status {
+	t.Helper()
+	status := r.Check()
+	if len(status) != 1 {
+		t.Fatalf("Check() returned %d workers, want 1", len(status))
+	}
+	return status[0]
+}
+
+func TestAliveAndStalled(t *testing.T) {
+	r, clock := newTestRegistry(nil)
+	h := r.Register("w", time.Second)
+
+	if s := check(t, r); !s.Alive || s.Stalled || s.Beats != 1 || s.SinceBeat != 0 {
+		t.Errorf("after Register: %+v, want alive after one beat", s)
+	}
+
+	clock.advance(time.Second)
+	if s := check(t, r); !s.Alive || s.Stalled {
+		t.Errorf("at the threshold: %+v, want alive", s)
+	}
+
+	clock.advance(time.Millisecond)
+	s := check(t, r)
+	if s.Alive || !s.Stalled || s.SinceBeat != time.Second+time.Millisecond || s.Threshold != time.Second {
+		t.Errorf("past the threshold: %+v, want stalled", s)
+	}
+
+	h.Beat()
+	if s := check(t, r); !s.Alive || s.Stalled || s.Beats != 2 || !s.LastBeat.Equal(clock.now()) {
+		t.Errorf("after a beat: %+v, want alive again", s)
+	}
+
+	h.Expect(time.Hour)
+	clock.advance(time.Minute)
+	if s := check(t, r); s.Stalled || s.Threshold != time.Hour {
+		t.Errorf("after Expect(1h): %+v, want the new threshold", s)
+	}
+}
+
+func TestReady(t *testing.T) {
+	r, clock := newTestRegistry(nil)
+	h := r.Register("w", time.Second)
+
+	if check(t, r).Ready {
+		t.Error("ready before SetReady(true)")
+	}
+	h.SetReady(true)
+	if !check(t, r).Ready {
+		t.Error("not ready after SetReady(true)")
+	}
+
+	clock.advance(2 * time.Second)
+	if check(t, r).Ready {
+		t.Error("ready while stalled")
+	}
+	h.Beat()
+	if !check(t, r).Ready {
+		t.Error("not ready after beating again")
+	}
+
+	h.Stop()
+	clock.advance(time.Hour)
+	if s := check(t, r); s.Alive || s.Ready || s.Stalled || !s.Stopped {
+		t.Errorf("after Stop: %+v, want stopped, and neither alive nor stalled", s)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	r, clock := newTestRegistry(nil)
+	h := r.Register("w", time.Second)
+
+	h.Pause()
+	clock.advance(time.Hour)
+	if s := check(t, r); !s.Paused || s.Stalled || !s.Alive {
+		t.Errorf("paused for an hour: %+v, want paused and alive", s)
+	}
+
+	// Resuming counts as a beat: the hour paused doesn't stall the worker.
+	h.Resume()
+	if s := check(t, r); s.Paused || s.Stalled || s.SinceBeat != 0 || s.Beats != 2 {
+		t.Errorf("after Resume: %+v, want a fresh beat", s)
+	}
+
+	clock.advance(2 * time.Second)
+	if !check(t, r).Stalled {
+		t.Error("not stalled once resumed and silent past the threshold")
+	}
+}
+
+func TestRegisterReplaces(t *testing.T) {
+	r, clock := newTestRegistry(nil)
+	old := r.Register("a", time.Second)
+	r.Register("b", time.Hour)
+	clock.advance(2 * time.Second)
+
+	// a restarts: its new heartbeat takes the old one's place.
+	a := r.Register("a", time.Minute)
+	old.Stop()
+
+	status := r.Check()
+	if len(status) != 2 || status[0].Name != "a" || status[1].Name != "b" {
+		t.Fatalf("Check() = %+v, want a then b", status)
+	}
+	if s := status[0]; s.Stopped || s.Stalled || s.Beats != 1 || s.Threshold != time.Minute {
+		t.Errorf("a = %+v, want the new heartbeat, unaffected by the old one", s)
+	}
+
+	a.Stop()
+	if !r.Check()[0].Stopped {
+		t.Error("a's new heartbeat doesn't report its Stop")
+	}
+}
+
+func TestNil(t *testing.T) {
+	var r *Registry
+	h := r.Register("w", time.Second)
+	if h != nil {
+		t.Fatalf("nil Registry returned %v, want a nil Heartbeat", h)
+	}
+	// None of these may panic.
+	h.Beat()
+	h.Expect(time.Minute)
+	h.Pause()
+	h.Resume()
+	h.SetReady(true)
+	h.Stop()
+}
+
+// syncBuffer is a bytes.Buffer the Detect goroutine writes to while the
+// test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDetect(t *testing.T) {
+	var out syncBuffer
+	r, clock := newTestRegistry(tracing.NewText(&out))
+	h := r.Register("w", time.Second)
+	r.Register("idle", time.Second).Pause()
+
+	stalls := make(chan Status, 10)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Detect(quit, time.Millisecond, func(s Status) { stalls <- s })
+	}()
+
+	clock.advance(1500 * time.Millisecond)
+	select {
+	case s := <-stalls:
+		if s.Name != "w" || !s.Stalled {
+			t.Errorf("onStall(%+v), want w stalled", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("onStall was not called")
+	}
+
+	time.Sleep(20 * time.Millisecond) // Many more checks: still one stall.
+	h.Beat()
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(out.String(), "w is beating again") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no recovery traced; trace:\n%s", out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(quit)
+	<-done
+
+	if n := len(stalls); n != 0 {
+		t.Errorf("onStall called %d more times, want once per stall", n)
+	}
+	want := "   [Health]: w stalled: no beat for 1.5s\n   [Health]: w is beating again\n"
+	if got := out.String(); got != want {
+		t.Errorf("trace:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name                string
+		setup               func(h *Heartbeat, clock *fakeClock)
+		wantLive, wantReady int
+	}{
+		{"starting", func(*Heartbeat, *fakeClock) {}, http.StatusOK, http.StatusServiceUnavailable},
+		{"ready", func(h *Heartbeat, _ *fakeClock) { h.SetReady(true) }, http.StatusOK, http.StatusOK},
+		{"stalled", func(h *Heartbeat, clock *fakeClock) {
+			h.SetReady(true)
+			clock.advance(2 * time.Second)
+		}, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		{"paused", func(h *Heartbeat, clock *fakeClock) {
+			h.SetReady(true)
+			h.Pause()
+			clock.advance(time.Hour)
+		}, http.StatusOK, http.StatusOK},
+		{"stopped", func(h *Heartbeat, _ *fakeClock) { h.Stop() }, http.StatusOK, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, clock := newTestRegistry(nil)
+			r.Register("steady", time.Hour).SetReady(true)
+			tt.setup(r.Register("w", time.Second), clock)
+
+			for path, want := range map[string]int{"/livez": tt.wantLive, "/readyz": tt.wantReady} {
+				rec := httptest.NewRecorder()
+				r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+				if rec.Code != want {
+					t.Errorf("%s: %d, want %d", path, rec.Code, want)
+				}
+				if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("%s: Content-Type %q", path, ct)
+				}
+				var rep report
+				if err := json.NewDecoder(rec.Body).Decode(&rep); err != nil {
+					t.Fatalf("%s: %v", path, err)
+				}
+				wantStatus := "ok"
+				if want != http.StatusOK {
+					wantStatus = "fail"
+				}
+				if rep.Status != wantStatus || len(rep.Workers) != 2 || rep.Workers[1].Name != "w" {
+					t.Errorf("%s: %+v, want status %q and both workers", path, rep, wantStatus)
+				}
+			}
+		})
+	}
+}
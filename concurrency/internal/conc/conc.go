@@ -0,0 +1,61 @@
+// Package conc holds the few helpers that pool, supervisor and schedule
+// all need: the exponential backoff and panic recovery. It is internal so
+// that each package can keep exporting them under its own name.
+package conc
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// Backoff computes the delay before a retry: Base, then Base*Multiplier,
+// Base*Multiplier², ... capped at Max, with a random Jitter share removed
+// so that tasks that failed together don't all come back at the same time.
+type Backoff struct {
+	Base       time.Duration // Delay before the first retry.
+	Max        time.Duration // Upper bound of the delay; 0 means no bound.
+	Multiplier float64       // Growth factor per attempt; 0 means 2.
+	Jitter     float64       // Share of the delay that is randomised, between 0 and 1.
+}
+
+// Delay returns the delay before retry number 'retry' (starting at 1).
+// r is a random number in [0, 1) used for the jitter.
+func (b Backoff) Delay(retry int, r float64) time.Duration {
+	mult := b.Multiplier
+	if mult == 0 {
+		mult = 2
+	}
+
+	d := float64(b.Base)
+	for i := 1; i < retry; i++ {
+		d *= mult
+		if b.Max > 0 && d >= float64(b.Max) {
+			break
+		}
+	}
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+
+	// "Equal jitter": keep (1-Jitter) of the delay, randomise the rest.
+	return time.Duration(d * (1 - b.Jitter*r))
+}
+
+// Panic is a panic recovered by Try.
+type Panic struct {
+	Value any    // The value passed to panic().
+	Stack []byte // The stack of the panicking goroutine.
+}
+
+// Try runs f and returns the panic it raised, or nil if it returned.
+// Callers turn the Panic into their own error type.
+func Try(f func()) (p *Panic) {
+	defer func() {
+		if r := recover(); r != nil {
+			p = &Panic{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	f()
+	return nil
+}
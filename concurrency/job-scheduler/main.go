@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"advanced-concepts/concurrency/schedule"
+)
+
+// --- Jobs ---
+
+// slow is a job that takes 'd', longer than its interval, so that its
+// runs overlap. It returns early when the scheduler is stopping.
+func slow(d time.Duration) func(<-chan struct{}) error {
+	return func(quit <-chan struct{}) error {
+		select {
+		case <-time.After(d):
+		case <-quit:
+		}
+		return nil
+	}
+}
+
+// flaky starts failing on its 5th run.
+func flaky() func(<-chan struct{}) error {
+	var n atomic.Int32
+	return func(<-chan struct{}) error {
+		if run := n.Add(1); run >= 5 {
+			return fmt.Errorf("run %d: %w", run, errors.New("upstream returned 503"))
+		}
+		return nil
+	}
+}
+
+// printStatus prints the Status table of s.
+func printStatus(s *schedule.Scheduler, start time.Time) {
+	fmt.Printf("   %-10s %6s %4s %7s %6s %9s %9s  %s\n",
+		"job", "paused", "runs", "skipped", "queued", "last run", "next run", "last error")
+	for _, j := range s.Status() {
+		at := func(t time.Time) string {
+			if t.IsZero() {
+				return "-"
+			}
+			return t.Sub(start).Round(10 * time.Millisecond).String()
+		}
+		last := "-"
+		if j.LastError != nil {
+			last = j.LastError.Error()
+		}
+		fmt.Printf("   %-10s %6v %4d %7d %6d %9s %9s  %s\n",
+			j.Name, j.Paused, j.Runs, j.Skipped, j.Queued, at(j.LastRun), at(j.NextRun), last)
+	}
+}
+
+// --- Main Function to Run the Scheduler ---
+
+func main() {
+	fmt.Println("1. Cron expressions: the next three runs after 2024-02-28 23:58")
+	from := time.Date(2024, 2, 28, 23, 58, 0, 0, time.UTC)
+	for _, expr := range []string{"*/15 * * * *", "30 9 * * 1-5", "0 0 29 2 *", "@monthly", "0 12 1 * 0"} {
+		c, err := schedule.Cron(expr)
+		if err != nil {
+			fmt.Printf("   %-14s %v\n", expr, err)
+			continue
+		}
+		fmt.Printf("   %-14s", expr)
+		t := from
+		for i := 0; i < 3; i++ {
+			t = c.Next(t)
+			fmt.Printf("  %s", t.Format("Mon 2006-01-02 15:04"))
+		}
+		fmt.Println()
+	}
+	if _, err := schedule.Cron("61 * * * *"); err != nil {
+		fmt.Printf("   invalid: %v\n", err)
+	}
+
+	fmt.Println("\n2. Intervals, jitter, overlap policies, errors, pause and resume")
+	quit := make(chan struct{})
+	s := schedule.New(quit, nil)
+	start := time.Now()
+
+	s.Add(schedule.Job{Name: "tick", Schedule: schedule.Every(50 * time.Millisecond), Run: slow(0)})
+	s.Add(schedule.Job{Name: "jittered", Schedule: schedule.WithJitter(schedule.Every(50*time.Millisecond), 50*time.Millisecond), Run: slow(0)})
+	s.Add(schedule.Job{Name: "skip", Schedule: schedule.Every(50 * time.Millisecond), Overlap: schedule.Skip, Run: slow(120 * time.Millisecond)})
+	s.Add(schedule.Job{Name: "queue", Schedule: schedule.Every(50 * time.Millisecond), Overlap: schedule.Queue, Run: slow(120 * time.Millisecond)})
+	s.Add(schedule.Job{Name: "flaky", Schedule: schedule.Every(50 * time.Millisecond), Run: flaky()})
+	s.Add(schedule.Job{Name: "paused", Schedule: schedule.Every(50 * time.Millisecond), Run: slow(0)})
+
+	time.Sleep(120 * time.Millisecond)
+	s.Pause("paused")
+	time.Sleep(280 * time.Millisecond)
+
+	fmt.Println("   after 400ms ('paused' paused at 120ms):")
+	printStatus(s, start)
+
+	s.Resume("paused")
+	time.Sleep(200 * time.Millisecond)
+	fmt.Println("\n   after 600ms ('paused' resumed at 400ms):")
+	printStatus(s, start)
+
+	fmt.Printf("\n   Pause of an unknown job: %v\n", s.Pause("nope"))
+
+	// --- The Quit-Channel Shutdown ---
+	close(quit)
+	<-s.Done()
+	fmt.Printf("   stopped after %v; Add now fails: %v\n",
+		time.Since(start).Round(10*time.Millisecond), s.Add(schedule.Job{Name: "late"}))
+}
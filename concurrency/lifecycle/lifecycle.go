@@ -0,0 +1,259 @@
+// Package lifecycle starts long-running components in dependency order and
+// stops them in reverse order when the process is asked to stop.
+//
+// It replaces the "sleep for a while, then defer w.close()" of the
+// stop-go-routine example with what a service does: run until SIGINT or
+// SIGTERM, then shut down gracefully, and exit right away if a second
+// signal shows that the operator has run out of patience.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"advanced-concepts/concurrency/tracing"
+)
+
+// Component is something with a start and a stop, such as a watcher.
+type Component struct {
+	Name      string
+	DependsOn []string // Components that must be started before this one.
+
+	// Start must return once the component is running. ctx is the one
+	// passed to Run. nil means nothing to do.
+	Start func(ctx context.Context) error
+
+	// Stop must return once the component has stopped, or give up when
+	// ctx, which carries the StopTimeout, is done. nil means nothing to do.
+	Stop func(ctx context.Context) error
+}
+
+// Config configures a Manager. The zero value is ready to use.
+type Config struct {
+	// Signals trigger the shutdown; a second one forces an immediate exit.
+	// They default to SIGINT and SIGTERM.
+	Signals []os.Signal
+
+	// StopTimeout bounds each component's Stop. It defaults to 5 seconds.
+	StopTimeout time.Duration
+
+	// Exit is called with status 1 on the second signal. It defaults to
+	// os.Exit; if it returns (as a test hook would), Run returns ErrForcedExit.
+	Exit func(code int)
+
+	// Trace receives the manager's events; nil means none.
+	Trace *tracing.Tracer
+}
+
+// ErrForcedExit is returned by Run when a second signal interrupted the
+// shutdown and Config.Exit returned.
+var ErrForcedExit = errors.New("lifecycle: forced exit on second signal")
+
+// StopResult is how the Stop of one component went.
+type StopResult struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// Report is what Run did.
+type Report struct {
+	Signal os.Signal    // The signal that triggered the shutdown, or nil.
+	Stops  []StopResult // In stop order: the reverse of the start order.
+}
+
+// Manager runs components from start to stop.
+type Manager struct {
+	cfg        Config
+	trace      func(event, msg string, attrs ...slog.Attr)
+	components []Component
+	byName     map[string]int
+}
+
+// New returns a Manager with no components.
+func New(cfg Config) *Manager {
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	if cfg.StopTimeout <= 0 {
+		cfg.StopTimeout = 5 * time.Second
+	}
+	if cfg.Exit == nil {
+		cfg.Exit = os.Exit
+	}
+
+	return &Manager{
+		cfg:    cfg,
+		trace:  cfg.Trace.For(tracing.Indented("Lifecycle")),
+		byName: make(map[string]int),
+	}
+}
+
+// Register adds c. Its dependencies may be registered later, but before Run.
+func (m *Manager) Register(c Component) error {
+	if _, dup := m.byName[c.Name]; dup {
+		return fmt.Errorf("lifecycle: component %q registered twice", c.Name)
+	}
+	m.byName[c.Name] = len(m.components)
+	m.components = append(m.components, c)
+	return nil
+}
+
+// Run starts every component, dependencies first, and waits for a signal
+// or for ctx to be done. Then it stops the started components in reverse
+// order, each within the StopTimeout, and reports how long each took.
+//
+// If a Start fails, the components already started are stopped and Run
+// returns the error, joined with those of the Stops, if any. If a second
+// signal arrives during the shutdown, Run calls Config.Exit(1) without
+// waiting for the remaining Stops.
+func (m *Manager) Run(ctx context.Context) (*Report, error) {
+	order, err := m.order()
+	if err != nil {
+		return nil, err
+	}
+
+	// Listen before starting anything, so that an early signal isn't lost.
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, m.cfg.Signals...)
+	defer signal.Stop(sigs)
+
+	report := &Report{}
+
+	// --- Start, Dependencies First ---
+	var startErr error
+	started := 0
+	for _, c := range order {
+		m.trace("start", "starting "+c.Name, slog.String("component", c.Name))
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				startErr = fmt.Errorf("lifecycle: start %s: %w", c.Name, err)
+				m.trace("start-failed", startErr.Error(), slog.String("component", c.Name))
+				break
+			}
+		}
+		started++
+	}
+
+	// --- Run Until Told to Stop ---
+	if startErr == nil {
+		m.trace("running", "all components started; waiting for a signal")
+		select {
+		case sig := <-sigs:
+			report.Signal = sig
+			m.trace("signal", fmt.Sprintf("received %v, shutting down", sig), slog.String("signal", sig.String()))
+		case <-ctx.Done():
+			m.trace("cancel", "context done, shutting down")
+		}
+	}
+
+	// --- Stop in Reverse Order, Unless Forced Out ---
+	stops := make(chan StopResult)
+	go func() {
+		defer close(stops)
+		for i := started - 1; i >= 0; i-- {
+			stops <- m.stop(order[i])
+		}
+	}()
+
+	for {
+		select {
+		case r, ok := <-stops:
+			if !ok {
+				return report, errors.Join(startErr, stopErrors(report))
+			}
+			report.Stops = append(report.Stops, r)
+
+		case sig := <-sigs:
+			m.trace("force", fmt.Sprintf("received %v again, exiting now", sig), slog.String("signal", sig.String()))
+			m.cfg.Exit(1)
+
+			// Exit returned: drain the remaining stops in the background.
+			go func() {
+				for range stops {
+				}
+			}()
+			return report, ErrForcedExit
+		}
+	}
+}
+
+// stop runs the Stop of c within the StopTimeout and times it.
+func (m *Manager) stop(c Component) StopResult {
+	m.trace("stop", "stopping "+c.Name, slog.String("component", c.Name))
+
+	start := time.Now()
+	var err error
+	if c.Stop != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), m.cfg.StopTimeout)
+		err = c.Stop(ctx)
+		cancel()
+	}
+	r := StopResult{Name: c.Name, Duration: time.Since(start), Err: err}
+
+	m.trace("stopped", fmt.Sprintf("stopped %s in %v (err=%v)", c.Name, r.Duration.Round(time.Millisecond), err),
+		slog.String("component", c.Name), slog.Duration("duration", r.Duration))
+	return r
+}
+
+// stopErrors joins the errors of the Stops in r, with the component names.
+func stopErrors(r *Report) error {
+	var errs []error
+	for _, s := range r.Stops {
+		if s.Err != nil {
+			errs = append(errs, fmt.Errorf("lifecycle: stop %s: %w", s.Name, s.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// order returns the components in start order: every component after its
+// dependencies, and otherwise in registration order.
+func (m *Manager) order() ([]Component, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(m.components))
+	order := make([]Component, 0, len(m.components))
+
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		c := m.components[i]
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("lifecycle: dependency cycle: %s -> %s", strings.Join(path, " -> "), c.Name)
+		}
+
+		state[i] = visiting
+		for _, dep := range c.DependsOn {
+			j, ok := m.byName[dep]
+			if !ok {
+				return fmt.Errorf("lifecycle: %s depends on unknown component %q", c.Name, dep)
+			}
+			if err := visit(j, append(path, c.Name)); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		order = append(order, c)
+		return nil
+	}
+
+	for i := range m.components {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,228 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/lifecycle"
+)
+
+// recorder notes the starts and stops of components, in order.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) note(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+// component returns a Component that records its start and stop in r.
+// onStart and onStop, if not nil, run after the record is made; onStop's
+// error is the Stop's.
+func (r *recorder) component(name string, onStart func() error, onStop func(context.Context) error, dependsOn ...string) lifecycle.Component {
+	return lifecycle.Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			r.note("start " + name)
+			if onStart != nil {
+				return onStart()
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			r.note("stop " + name)
+			if onStop != nil {
+				return onStop(ctx)
+			}
+			return nil
+		},
+	}
+}
+
+// signalSelf sends sig to this very process, as an operator pressing
+// Ctrl-C or an orchestrator stopping us would.
+func signalSelf(t *testing.T, sig syscall.Signal) func() error {
+	return func() error {
+		if err := syscall.Kill(os.Getpid(), sig); err != nil {
+			t.Errorf("kill: %v", err)
+		}
+		return nil
+	}
+}
+
+// hang is a Stop that never finishes its cleanup: it gives up when the
+// StopTimeout expires.
+func hang(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// stopNames returns the names of the components in r's stop order.
+func stopNames(r *lifecycle.Report) []string {
+	var names []string
+	for _, s := range r.Stops {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestRunStopsInReverseOrderOnSignal(t *testing.T) {
+	var rec recorder
+	m := lifecycle.New(lifecycle.Config{})
+	// Registered out of order: the dependencies decide. The last component
+	// to start signals the process, so the signal can't come too early.
+	m.Register(rec.component("api", signalSelf(t, syscall.SIGTERM), nil, "cache", "db"))
+	m.Register(rec.component("cache", nil, nil, "db"))
+	m.Register(rec.component("db", nil, nil))
+
+	r, err := m.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if r.Signal != syscall.SIGTERM {
+		t.Errorf("Signal = %v, want %v", r.Signal, syscall.SIGTERM)
+	}
+	want := []string{"start db", "start cache", "start api", "stop api", "stop cache", "stop db"}
+	if got := rec.get(); !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	if got := stopNames(r); !slices.Equal(got, []string{"api", "cache", "db"}) {
+		t.Errorf("report stops = %q, want api, cache, db", got)
+	}
+}
+
+func TestRunStopTimeout(t *testing.T) {
+	var rec recorder
+	m := lifecycle.New(lifecycle.Config{StopTimeout: 20 * time.Millisecond})
+	m.Register(rec.component("db", nil, hang))
+	m.Register(rec.component("api", signalSelf(t, syscall.SIGINT), nil, "db"))
+
+	r, err := m.Run(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stop db") {
+		t.Errorf("Run: %v, want db's stop to time out", err)
+	}
+	if len(r.Stops) != 2 || r.Stops[1].Name != "db" || r.Stops[1].Duration < 20*time.Millisecond {
+		t.Errorf("stops = %+v, want db last, after its StopTimeout", r.Stops)
+	}
+}
+
+func TestRunSecondSignalForcesExit(t *testing.T) {
+	var rec recorder
+	exited := make(chan int, 1)
+	m := lifecycle.New(lifecycle.Config{
+		StopTimeout: time.Second,
+		Exit:        func(code int) { exited <- code },
+	})
+	// The operator runs out of patience while db is cleaning up.
+	m.Register(rec.component("db", nil, func(ctx context.Context) error {
+		signalSelf(t, syscall.SIGINT)()
+		return hang(ctx)
+	}))
+	m.Register(rec.component("api", signalSelf(t, syscall.SIGINT), nil, "db"))
+
+	r, err := m.Run(context.Background())
+	if !errors.Is(err, lifecycle.ErrForcedExit) {
+		t.Errorf("Run: %v, want %v", err, lifecycle.ErrForcedExit)
+	}
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Errorf("Exit(%d), want Exit(1)", code)
+		}
+	default:
+		t.Error("Exit was not called")
+	}
+	if got := stopNames(r); !slices.Equal(got, []string{"api"}) {
+		t.Errorf("report stops = %q, want only api, the one that finished", got)
+	}
+}
+
+func TestRunContextDone(t *testing.T) {
+	var rec recorder
+	ctx, cancel := context.WithCancel(context.Background())
+	m := lifecycle.New(lifecycle.Config{})
+	m.Register(rec.component("db", func() error { cancel(); return nil }, nil))
+
+	r, err := m.Run(ctx)
+	if err != nil || r.Signal != nil {
+		t.Errorf("Run: %v, signal %v; want nil, nil", err, r.Signal)
+	}
+	if got := rec.get(); !slices.Equal(got, []string{"start db", "stop db"}) {
+		t.Errorf("events = %q, want db started and stopped", got)
+	}
+}
+
+func TestRunStartFailure(t *testing.T) {
+	var rec recorder
+	errNoDisk := errors.New("no disk")
+	m := lifecycle.New(lifecycle.Config{})
+	m.Register(rec.component("db", nil, nil))
+	m.Register(rec.component("cache", nil, nil, "db"))
+	m.Register(rec.component("api", func() error { return errNoDisk }, nil, "cache"))
+
+	_, err := m.Run(context.Background())
+	if !errors.Is(err, errNoDisk) {
+		t.Errorf("Run: %v, want %v", err, errNoDisk)
+	}
+	// api never started, so only the others are stopped.
+	want := []string{"start db", "start cache", "start api", "stop cache", "stop db"}
+	if got := rec.get(); !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestRunRefusesBadDependencies(t *testing.T) {
+	tests := []struct {
+		name       string
+		components [][]string // Name, then dependencies.
+		wantErr    string
+	}{
+		{"cycle", [][]string{{"a", "b"}, {"b", "a"}}, "dependency cycle: a -> b -> a"},
+		{"unknown", [][]string{{"a", "ghost"}}, `a depends on unknown component "ghost"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rec recorder
+			m := lifecycle.New(lifecycle.Config{})
+			for _, c := range tt.components {
+				m.Register(rec.component(c[0], nil, nil, c[1:]...))
+			}
+
+			_, err := m.Run(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run: %v, want %q", err, tt.wantErr)
+			}
+			if got := rec.get(); len(got) != 0 {
+				t.Errorf("events = %q, want nothing started", got)
+			}
+		})
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	m := lifecycle.New(lifecycle.Config{})
+	if err := m.Register(lifecycle.Component{Name: "db"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := m.Register(lifecycle.Component{Name: "db"}); err == nil {
+		t.Error("Register accepted a second db")
+	}
+}
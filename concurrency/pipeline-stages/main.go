@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"advanced-concepts/concurrency/pipeline"
+)
+
+// --- The Records ---
+
+// event is a parsed line of the click log.
+type event struct {
+	user   int
+	action string
+}
+
+// enriched is an event with the data looked up for its user.
+type enriched struct {
+	event
+	country string
+}
+
+var countries = []string{"BE", "DE", "FR", "NL"}
+
+// --- The Stages ---
+
+// readLines is the source: it emits n log lines, one of them broken
+// if 'broken' is not negative.
+func readLines(n, broken int) func(context.Context, func(string) error) error {
+	return func(ctx context.Context, emit func(string) error) error {
+		actions := []string{"view", "click", "buy"}
+		for i := 0; i < n; i++ {
+			line := fmt.Sprintf("user-%d,%s", i%50, actions[i%len(actions)])
+			if i == broken {
+				line = "garbage"
+			}
+			if err := emit(line); err != nil {
+				return err // Cancelled: stop producing.
+			}
+		}
+		return nil
+	}
+}
+
+// parse turns a line into an event. It is cheap.
+func parse(_ context.Context, line string) (event, error) {
+	user, action, ok := strings.Cut(line, ",")
+	if !ok {
+		return event{}, fmt.Errorf("malformed line %q", line)
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(user, "user-"))
+	if err != nil {
+		return event{}, err
+	}
+	return event{user: id, action: action}, nil
+}
+
+// enrich looks the user up in a slow "database": it is I/O bound
+// and deserves many workers.
+func enrich(ctx context.Context, e event) (enriched, error) {
+	select {
+	case <-time.After(5 * time.Millisecond):
+	case <-ctx.Done():
+		return enriched{}, ctx.Err()
+	}
+	return enriched{event: e, country: countries[e.user%len(countries)]}, nil
+}
+
+// counter is the sink: a single writer, so it needs no lock.
+type counter map[string]int
+
+func (c counter) write(_ context.Context, e enriched) error {
+	c[e.country+"/"+e.action]++
+	return nil
+}
+
+// build assembles read → parse → enrich → write.
+func build(lines, broken int, out counter) *pipeline.Pipeline {
+	src := pipeline.Source(pipeline.Config{Name: "read", Buffer: 16}, readLines(lines, broken))
+	parsed := pipeline.Then(src, pipeline.Config{Name: "parse", Workers: 2, Buffer: 16}, parse)
+	withCountry := pipeline.Then(parsed, pipeline.Config{Name: "enrich", Workers: 32, Buffer: 16}, enrich)
+	return pipeline.Sink(withCountry, pipeline.Config{Name: "write", Workers: 1}, out.write)
+}
+
+// printStats prints one line per stage.
+func printStats(stats []pipeline.StageStats) {
+	fmt.Printf("   %-8s %7s %6s %6s %10s %6s\n", "stage", "workers", "in", "out", "elapsed", "util")
+	for _, s := range stats {
+		fmt.Printf("   %-8s %7d %6d %6d %10v %5.0f%%\n",
+			s.Name, s.Workers, s.In, s.Out, s.Elapsed.Round(time.Millisecond), 100*s.Utilisation())
+	}
+}
+
+// --- Main Function to Run the Pipelines ---
+
+func main() {
+	before := runtime.NumGoroutine()
+
+	fmt.Println("1. A complete run: closing propagates from the source to the sink")
+	out := counter{}
+	stats, err := build(600, -1, out).Run(context.Background())
+	printStats(stats)
+	fmt.Printf("   err=%v, %d country/action pairs, BE/buy=%d\n", err, len(out), out["BE/buy"])
+
+	fmt.Println("\n2. A malformed line: the error cancels every stage upstream")
+	stats, err = build(600, 100, counter{}).Run(context.Background())
+	printStats(stats)
+	fmt.Printf("   err=%v\n", err)
+
+	fmt.Println("\n3. A deadline: cancellation stops the source and drains the stages")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	stats, err = build(100_000, -1, counter{}).Run(ctx)
+	printStats(stats)
+	fmt.Printf("   err=%v, deadline: %v\n", err, errors.Is(err, context.DeadlineExceeded))
+
+	// Every stage goroutine and pool worker is gone once Run returns.
+	time.Sleep(10 * time.Millisecond)
+	fmt.Printf("\nGoroutines before: %d, after: %d\n", before, runtime.NumGoroutine())
+}
@@ -0,0 +1,262 @@
+// Package pipeline chains worker pools into stages (parse → enrich → write)
+// without the hand-written WaitGroup and close dance between them.
+//
+// Every stage is a pool.Pool with its own concurrency, connected to the next
+// stage by a buffered channel:
+//   - closing propagates downstream: when a stage's input is closed and its
+//     workers are done, it closes its output;
+//   - errors and cancellation propagate upstream: the first error cancels
+//     the context shared by every stage, which stops the source and
+//     unblocks every send;
+//   - a panic in a stage function doesn't crash the process: the value is
+//     dropped, the stage carries on with the others, and once it is done
+//     the pipeline fails with an error wrapping the *pool.PanicError;
+//   - a panic in the source ends it, and fails the pipeline the same way.
+//
+// Stages are typed when the pipeline is built:
+//
+//	src := pipeline.Source(pipeline.Config{Name: "read", Buffer: 16}, readLines)
+//	parsed := pipeline.Then(src, pipeline.Config{Name: "parse", Workers: 4, Buffer: 16}, parse)
+//	p := pipeline.Sink(parsed, pipeline.Config{Name: "write", Workers: 1}, write)
+//	stats, err := p.Run(ctx)
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"advanced-concepts/concurrency/internal/conc"
+	"advanced-concepts/concurrency/pool"
+)
+
+// Config is how a stage runs.
+type Config struct {
+	Name    string
+	Workers int // Concurrency of the stage; at least 1. Ignored by Source.
+	Buffer  int // Capacity of the channel to the next stage. Ignored by Sink.
+}
+
+// StageStats is what one stage did during a Run.
+type StageStats struct {
+	Name    string
+	Workers int
+	In      int64         // Values received from the previous stage.
+	Out     int64         // Values sent to the next stage.
+	Dropped int64         // Values lost to a panic in the stage function.
+	Busy    time.Duration // Total time spent inside the stage function.
+	Elapsed time.Duration // From the start of Run until the stage closed its output.
+}
+
+// Utilisation is the share of the stage's worker time spent working.
+func (s StageStats) Utilisation() float64 {
+	if s.Elapsed == 0 || s.Workers == 0 {
+		return 0
+	}
+	return s.Busy.Seconds() / (s.Elapsed.Seconds() * float64(s.Workers))
+}
+
+// stage is a type-erased step of the pipeline.
+type stage struct {
+	cfg    Config
+	source func(ctx context.Context, emit func(any) error) error // Only set for the source.
+	fn     func(ctx context.Context, v any) (any, error)         // Only set for the other stages.
+	sink   bool                                                  // fn's results are discarded.
+}
+
+// Builder is a pipeline under construction whose last stage emits T.
+type Builder[T any] struct {
+	stages []stage
+}
+
+// Pipeline is a complete pipeline, from a Source to a Sink.
+type Pipeline struct {
+	stages []stage
+}
+
+// Source starts a pipeline with gen, which produces values by calling emit.
+// emit fails once the pipeline is cancelled; gen should then return.
+func Source[T any](cfg Config, gen func(ctx context.Context, emit func(T) error) error) Builder[T] {
+	cfg.Workers = 1
+	return Builder[T]{stages: []stage{{
+		cfg: cfg,
+		source: func(ctx context.Context, emit func(any) error) error {
+			return gen(ctx, func(v T) error { return emit(v) })
+		},
+	}}}
+}
+
+// Then appends a stage that runs fn over every value of b with cfg.Workers workers.
+func Then[In, Out any](b Builder[In], cfg Config, fn func(context.Context, In) (Out, error)) Builder[Out] {
+	return Builder[Out]{stages: append(clip(b.stages), stage{
+		cfg: checked(cfg),
+		fn: func(ctx context.Context, v any) (any, error) {
+			return fn(ctx, v.(In))
+		},
+	})}
+}
+
+// Sink ends the pipeline with a stage that consumes every value of b.
+func Sink[In any](b Builder[In], cfg Config, fn func(context.Context, In) error) *Pipeline {
+	cfg.Buffer = 0
+	return &Pipeline{stages: append(clip(b.stages), stage{
+		cfg:  checked(cfg),
+		sink: true,
+		fn: func(ctx context.Context, v any) (any, error) {
+			return nil, fn(ctx, v.(In))
+		},
+	})}
+}
+
+// clip makes sure that two stages appended to the same Builder
+// don't share (and overwrite) one backing array.
+func clip(stages []stage) []stage {
+	return stages[:len(stages):len(stages)]
+}
+
+// checked validates the concurrency of a stage.
+func checked(cfg Config) Config {
+	if cfg.Workers < 1 {
+		panic(fmt.Sprintf("pipeline: stage %q needs at least 1 worker", cfg.Name))
+	}
+	return cfg
+}
+
+// Run runs the pipeline until the source is exhausted and every value has
+// reached the sink, or until the first error or the cancellation of ctx.
+// It returns the statistics of every stage, in order, and the first error,
+// wrapped with the name of the stage it came from.
+func (p *Pipeline) Run(ctx context.Context) ([]StageStats, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	start := time.Now()
+	stats := make([]StageStats, len(p.stages))
+	counters := make([]counters, len(p.stages))
+
+	var wg sync.WaitGroup
+	wg.Add(len(p.stages))
+
+	// --- The Source ---
+	src := p.stages[0]
+	out := make(chan any, src.cfg.Buffer)
+	go func() {
+		defer wg.Done()
+		defer func() { stats[0].Elapsed = time.Since(start) }()
+		defer close(out) // Closing propagates downstream.
+
+		emit := func(v any) error {
+			select {
+			case out <- v:
+				counters[0].out.Add(1)
+				return nil
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			}
+		}
+		if err := runSource(ctx, src, emit); err != nil && ctx.Err() == nil {
+			cancel(fmt.Errorf("stage %s: %w", src.cfg.Name, err))
+		}
+	}()
+
+	// --- Every Other Stage ---
+	in := out
+	for i := 1; i < len(p.stages); i++ {
+		var next chan any
+		if !p.stages[i].sink {
+			next = make(chan any, p.stages[i].cfg.Buffer)
+		}
+		go func(i int, in <-chan any, out chan<- any) {
+			defer wg.Done()
+			defer func() { stats[i].Elapsed = time.Since(start) }()
+			runStage(ctx, cancel, p.stages[i], in, out, &counters[i])
+		}(i, in, next)
+		in = next
+	}
+
+	wg.Wait()
+
+	for i, s := range p.stages {
+		stats[i].Name = s.cfg.Name
+		stats[i].Workers = s.cfg.Workers
+		stats[i].In = counters[i].in.Load()
+		stats[i].Out = counters[i].out.Load()
+		stats[i].Dropped = counters[i].dropped.Load()
+		stats[i].Busy = time.Duration(counters[i].busy.Load())
+	}
+
+	if ctx.Err() != nil {
+		return stats, context.Cause(ctx)
+	}
+	return stats, nil
+}
+
+// runSource runs the source, turning a panic into a *pool.PanicError
+// like the pools of the other stages do.
+func runSource(ctx context.Context, s stage, emit func(any) error) (err error) {
+	if p := conc.Try(func() { err = s.source(ctx, emit) }); p != nil {
+		return &pool.PanicError{Value: p.Value, Stack: p.Stack, InputSize: -1}
+	}
+	return err
+}
+
+// counters are updated by the stage goroutines while the pipeline runs.
+type counters struct {
+	in, out, busy, dropped atomic.Int64
+}
+
+// runStage runs one stage as a worker pool between 'in' and 'out'.
+// 'out' is nil for the sink.
+func runStage(ctx context.Context, cancel context.CancelCauseFunc, s stage, in <-chan any, out chan<- any, c *counters) {
+	if out != nil {
+		defer close(out) // Closing propagates downstream.
+	}
+
+	p := pool.WithContext(ctx, s.cfg.Workers, func(ctx context.Context, v any) (any, error) {
+		start := time.Now()
+		defer func() { c.busy.Add(int64(time.Since(start))) }()
+		return s.fn(ctx, v)
+	})
+
+	// The feeder: the previous stage's output becomes this pool's input.
+	go func() {
+		defer p.Close()
+		for v := range in {
+			c.in.Add(1)
+			if p.Submit(v) != nil {
+				return // Cancelled: upstream unblocks through ctx.
+			}
+		}
+	}()
+
+	// The drain: this pool's results become the next stage's input.
+	// It keeps receiving after cancellation so that the pool can wind down.
+	for v := range p.Results() {
+		if out == nil {
+			continue
+		}
+		select {
+		case out <- v:
+			c.out.Add(1)
+		case <-ctx.Done():
+		}
+	}
+
+	// Errors propagate upstream: cancel the context every stage shares.
+	err := p.Wait()
+
+	// The pool isolates panics instead of failing: a value that made the
+	// stage panic is gone, so the run must not look like a success.
+	summary := p.Summary()
+	c.dropped.Store(int64(summary.Panicked))
+	if err == nil && len(summary.Errors) > 0 {
+		err = errors.Join(summary.Errors...)
+	}
+
+	if err != nil && ctx.Err() == nil {
+		cancel(fmt.Errorf("stage %s: %w", s.cfg.Name, err))
+	}
+}
@@ -0,0 +1,73 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"advanced-concepts/concurrency/pipeline"
+	"advanced-concepts/concurrency/pool"
+)
+
+func TestRunFailsOnStagePanic(t *testing.T) {
+	src := pipeline.Source(pipeline.Config{Name: "count", Buffer: 4}, func(ctx context.Context, emit func(int) error) error {
+		for i := 0; i < 20; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	halved := pipeline.Then(src, pipeline.Config{Name: "halve", Workers: 2, Buffer: 4}, func(_ context.Context, v int) (int, error) {
+		if v == 7 {
+			panic("odd one out")
+		}
+		return v / 2, nil
+	})
+	got := 0
+	p := pipeline.Sink(halved, pipeline.Config{Name: "collect", Workers: 1}, func(context.Context, int) error {
+		got++
+		return nil
+	})
+
+	stats, err := p.Run(context.Background())
+
+	var pe *pool.PanicError
+	if !errors.As(err, &pe) || pe.Value != "odd one out" {
+		t.Fatalf("err = %v, want a *pool.PanicError", err)
+	}
+	if stats[1].Dropped != 1 {
+		t.Errorf("stage %s dropped %d values, want 1", stats[1].Name, stats[1].Dropped)
+	}
+	if got > 19 {
+		t.Errorf("the sink received %d values, want at most 19", got)
+	}
+}
+
+func TestRunFailsOnSourcePanic(t *testing.T) {
+	src := pipeline.Source(pipeline.Config{Name: "count"}, func(ctx context.Context, emit func(int) error) error {
+		for i := 0; i < 3; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+		panic("source broke")
+	})
+	p := pipeline.Sink(src, pipeline.Config{Name: "collect", Workers: 1}, func(context.Context, int) error {
+		return nil
+	})
+
+	stats, err := p.Run(context.Background())
+
+	var pe *pool.PanicError
+	if !errors.As(err, &pe) || pe.Value != "source broke" || len(pe.Stack) == 0 {
+		t.Fatalf("err = %v, want a *pool.PanicError", err)
+	}
+	if !strings.HasPrefix(err.Error(), "stage count: ") {
+		t.Errorf("err = %q, want it to name the source", err)
+	}
+	if stats[0].Out != 3 {
+		t.Errorf("the source emitted %d values, want 3", stats[0].Out)
+	}
+}
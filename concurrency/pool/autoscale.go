@@ -0,0 +1,215 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// AutoscaleConfig bounds an autoscaling pool, see Autoscale.
+type AutoscaleConfig struct {
+	Min, Max int // The pool never has fewer than Min or more than Max workers.
+
+	// Interval is how often throughput and queue latency are sampled and
+	// the size is reconsidered. It defaults to 100ms.
+	Interval time.Duration
+
+	// TargetLatency is how long a task may wait in the queue before the
+	// pool considers itself too small. It defaults to 1ms.
+	TargetLatency time.Duration
+}
+
+// ScaleSample is one entry of the report of an autoscaling pool.
+type ScaleSample struct {
+	At           time.Duration // Time since the pool started.
+	Workers      int           // Pool size at the end of the interval.
+	Throughput   float64       // Tasks completed per second during the interval.
+	QueueLatency time.Duration // Average time a task waited for a worker.
+}
+
+// Autoscale replaces the fixed pool size with one that adapts to the workload.
+//
+// Instead of the caller choosing GOMAXPROCS for CPU-bound work and "a high
+// number" for I/O-bound work, the pool starts with n workers (clamped into
+// [Min, Max]) and hill-climbs:
+//   - tasks are waiting longer than TargetLatency: add workers,
+//   - the last growth did not raise throughput (the work is CPU-bound and
+//     the CPUs are saturated): undo it and hold that size for a while,
+//   - tasks don't wait at all: retire one idle worker.
+//
+// It panics if Min is less than 1 or Max is less than Min.
+func Autoscale(c AutoscaleConfig) Option {
+	if c.Min < 1 || c.Max < c.Min {
+		panic("pool: autoscale bounds must satisfy 1 <= Min <= Max")
+	}
+	if c.Interval <= 0 {
+		c.Interval = 100 * time.Millisecond
+	}
+	if c.TargetLatency <= 0 {
+		c.TargetLatency = time.Millisecond
+	}
+
+	return func(cfg *config) {
+		cfg.autoscale = &c
+	}
+}
+
+const (
+	// growthGain is the throughput gain a growth step must bring to be kept.
+	growthGain = 1.05
+	// holdIntervals is how long the pool keeps its size after a growth
+	// step turned out to be useless, before it probes again.
+	holdIntervals = 5
+)
+
+// scaler is the state of the autoscaling goroutine.
+type scaler struct {
+	cfg   AutoscaleConfig
+	start time.Time
+
+	mu     sync.Mutex
+	report []ScaleSample
+}
+
+// Size returns the current number of workers, not counting the ones that
+// have been asked to retire and will exit after their current task.
+func (p *Pool[In, Out]) Size() int {
+	return int(p.size.Load() - p.excess.Load())
+}
+
+// ScaleReport returns the size chosen by an autoscaling pool over time,
+// one sample per interval. It is nil for a fixed-size pool.
+func (p *Pool[In, Out]) ScaleReport() []ScaleSample {
+	if p.scaler == nil {
+		return nil
+	}
+
+	p.scaler.mu.Lock()
+	defer p.scaler.mu.Unlock()
+	return append([]ScaleSample(nil), p.scaler.report...)
+}
+
+// grow starts k more workers.
+//
+// The autoscaling goroutine holds a slot in p.wg for as long as it runs,
+// so the counter can't be zero here and it is legal to Add to it even
+// while the closing goroutine is already in p.wg.Wait().
+func (p *Pool[In, Out]) grow(k int) {
+	p.wg.Add(k)
+	p.size.Add(int64(k))
+	for i := 0; i < k; i++ {
+		go p.worker()
+	}
+}
+
+// shrink retires k workers. Busy workers are never interrupted: idle
+// workers exit right away, busy ones after their current task.
+func (p *Pool[In, Out]) shrink(k int) {
+	for i := 0; i < k; i++ {
+		// 'retire' is unbuffered, so the send only succeeds if a worker
+		// is parked in its select, i.e. idle.
+		select {
+		case p.retire <- struct{}{}:
+			// Off the size right away, not when the worker gets to
+			// exit: the next interval must not count it again.
+			p.size.Add(-1)
+		default:
+			p.excess.Add(1)
+		}
+	}
+}
+
+// retiring reports whether the calling worker should exit because the pool
+// shrank while it was busy. Each call that returns true consumes one retirement.
+func (p *Pool[In, Out]) retiring() bool {
+	for {
+		e := p.excess.Load()
+		if e <= 0 {
+			return false
+		}
+		if p.excess.CompareAndSwap(e, e-1) {
+			return true
+		}
+	}
+}
+
+// autoscale is the autoscaling goroutine.
+func (p *Pool[In, Out]) autoscale() {
+	defer p.wg.Done()
+
+	s := p.scaler
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	var (
+		lastDone  = p.completed.Load()
+		lastWait  = p.waited.Load()
+		lastCount = p.dequeued.Load()
+		baseTput  float64 // The throughput the next decision is compared with.
+		grew      int     // Workers added by the previous step.
+		settling  bool    // The interval right after a growth step is not judged.
+		hold      int     // Intervals left before growing is allowed again.
+	)
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-p.closed:
+			// No more tasks: the workers drain the queue and exit on their own.
+			return
+		case <-ticker.C:
+		}
+
+		done, wait, count := p.completed.Load(), p.waited.Load(), p.dequeued.Load()
+		tput := float64(done-lastDone) / s.cfg.Interval.Seconds()
+		var latency time.Duration
+		switch {
+		case count > lastCount:
+			latency = time.Duration((wait - lastWait) / (count - lastCount))
+		case len(p.tasks) > 0:
+			// Nothing was picked up at all while tasks were queued:
+			// they have been waiting for the whole interval.
+			latency = s.cfg.Interval
+		}
+		lastDone, lastWait, lastCount = done, wait, count
+
+		size := p.Size()
+		switch {
+		case settling:
+			// New workers only start finishing tasks one task duration
+			// after they start: give them an interval before judging,
+			// and keep comparing against the throughput before the growth.
+			settling = false
+
+		case grew > 0 && tput < baseTput*growthGain:
+			// More workers didn't mean more work done: step back.
+			p.shrink(min(grew, size-s.cfg.Min))
+			grew, hold = 0, holdIntervals
+
+		case latency > s.cfg.TargetLatency && size < s.cfg.Max && hold == 0:
+			// Tasks are queuing: double the pool, up to Max.
+			grew = min(size, s.cfg.Max-size)
+			p.grow(grew)
+			settling, baseTput = true, tput
+
+		case latency == 0 && size > s.cfg.Min:
+			p.shrink(1)
+			grew, baseTput = 0, tput
+
+		default:
+			grew, baseTput = 0, tput
+		}
+		if hold > 0 {
+			hold--
+		}
+
+		s.mu.Lock()
+		s.report = append(s.report, ScaleSample{
+			At:           time.Since(s.start),
+			Workers:      p.Size(),
+			Throughput:   tput,
+			QueueLatency: latency,
+		})
+		s.mu.Unlock()
+	}
+}
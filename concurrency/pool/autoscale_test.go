@@ -0,0 +1,119 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+func TestAutoscaleBounds(t *testing.T) {
+	cfg := pool.AutoscaleConfig{Min: 2, Max: 8, Interval: 10 * time.Millisecond}
+
+	// A blocking workload: more workers means more throughput, so the
+	// pool grows, but never past Max.
+	var mu sync.Mutex
+	var running, peak int
+	p := pool.WithContext(context.Background(), 1, func(_ context.Context, v int) (int, error) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return v, nil
+	}, pool.Autoscale(cfg))
+
+	if n := p.Size(); n != cfg.Min {
+		t.Errorf("Size() = %d at the start, want n=1 clamped to Min=%d", n, cfg.Min)
+	}
+
+	var done atomic.Int64
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for range p.Results() {
+			done.Add(1)
+		}
+	}()
+	for _, v := range upTo(300) {
+		p.Submit(v)
+		if n := p.Size(); n < cfg.Min || n > cfg.Max {
+			t.Errorf("Size() = %d, want it within [%d, %d]", n, cfg.Min, cfg.Max)
+		}
+	}
+
+	// Idle with the pool still open: it shrinks back, one worker per
+	// interval, down to Min.
+	deadline := time.Now().Add(2 * time.Second)
+	for (done.Load() < 300 || p.Size() > cfg.Min) && time.Now().Before(deadline) {
+		time.Sleep(cfg.Interval)
+	}
+	if n := p.Size(); n != cfg.Min {
+		t.Errorf("Size() = %d once idle, want Min=%d", n, cfg.Min)
+	}
+	// After Close the workers exit as the queue drains, below Min.
+	report := p.ScaleReport()
+	p.Close()
+	<-drained
+	if n := done.Load(); n != 300 {
+		t.Errorf("%d results, want 300", n)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if peak > cfg.Max {
+		t.Errorf("%d tasks ran at once, want at most Max=%d", peak, cfg.Max)
+	}
+
+	if len(report) == 0 {
+		t.Fatal("empty ScaleReport")
+	}
+	grew, queued := false, false
+	for i, s := range report {
+		if s.Workers < cfg.Min || s.Workers > cfg.Max {
+			t.Errorf("sample %d: %d workers, want within [%d, %d]", i, s.Workers, cfg.Min, cfg.Max)
+		}
+		if i > 0 && s.At <= report[i-1].At {
+			t.Errorf("sample %d at %v, not after the previous one at %v", i, s.At, report[i-1].At)
+		}
+		if s.Throughput < 0 || s.QueueLatency < 0 {
+			t.Errorf("sample %d: %+v, want no negative figures", i, s)
+		}
+		grew = grew || s.Workers > cfg.Min
+		queued = queued || s.QueueLatency > time.Millisecond // The default TargetLatency.
+	}
+	if !grew || !queued {
+		t.Errorf("report %+v, want tasks queuing and the pool growing", report)
+	}
+}
+
+func TestScaleReportOfFixedPool(t *testing.T) {
+	p := pool.WithContext(context.Background(), 2, func(_ context.Context, v int) (int, error) { return v, nil })
+	p.Close()
+	drain(p.Results())
+	if r := p.ScaleReport(); r != nil {
+		t.Errorf("ScaleReport() = %v, want nil", r)
+	}
+}
+
+func TestAutoscalePanics(t *testing.T) {
+	for _, cfg := range []pool.AutoscaleConfig{{Min: 0, Max: 4}, {Min: 4, Max: 2}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Autoscale(%+v) did not panic", cfg)
+				}
+			}()
+			pool.Autoscale(cfg)
+		}()
+	}
+}
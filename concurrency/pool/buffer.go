@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// poisonByte is written over every buffer that goes back to the pool.
+// A task that broke the ownership contract and kept a reference to its
+// buffer sees a wall of 0xDB instead of silently reading another task's data.
+const poisonByte = 0xDB
+
+// poison is copied over released buffers, ChunkSize bytes at a time.
+var poison = bytes.Repeat([]byte{poisonByte}, ChunkSize)
+
+// chunk is what travels from the producer to the workers: the bytes the
+// task sees and, when buffers are recycled, the pooled buffer behind them.
+type chunk struct {
+	data []byte
+	buf  *buffer // nil unless the data lives in a bufferPool buffer.
+}
+
+// Len lets PanicError and TimeoutError report the size of the chunk.
+func (c chunk) Len() int {
+	return len(c.data)
+}
+
+// buffer is a pooled byte slice with a reference count.
+//
+// A task may outlive its first run: a retry runs it again, and a run
+// abandoned by TaskTimeout keeps going in the background. The task itself
+// holds one reference until it settles for good, and every run holds one
+// more while it executes, so the buffer is only recycled once nobody can
+// read it any more.
+type buffer struct {
+	b    []byte
+	refs atomic.Int32
+}
+
+// bufferPool is the sync.Pool the book's read() comment asks for.
+// It stores *buffer rather than []byte so that Put itself doesn't allocate.
+type bufferPool struct {
+	pool sync.Pool
+}
+
+func newBufferPool() *bufferPool {
+	return &bufferPool{
+		pool: sync.Pool{
+			New: func() any {
+				return &buffer{b: make([]byte, ChunkSize)}
+			},
+		},
+	}
+}
+
+// get borrows a buffer of at least ChunkSize bytes, holding one reference.
+func (bp *bufferPool) get() *buffer {
+	b := bp.pool.Get().(*buffer)
+	b.refs.Store(1)
+	return b
+}
+
+// hold takes one more reference on b.
+func (bp *bufferPool) hold(b *buffer) {
+	b.refs.Add(1)
+}
+
+// release drops one reference on b. The last one poisons b and gives it
+// back; b must not be used afterwards.
+func (bp *bufferPool) release(b *buffer) {
+	if b.refs.Add(-1) > 0 {
+		return
+	}
+	for buf := b.b[:cap(b.b)]; len(buf) > 0; {
+		n := copy(buf, poison)
+		buf = buf[n:]
+	}
+	bp.pool.Put(b)
+}
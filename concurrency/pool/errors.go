@@ -0,0 +1,82 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTaskTimeout matches every TimeoutError with errors.Is.
+var ErrTaskTimeout = errors.New("pool: task timed out")
+
+// PanicError is a panic raised by a task and recovered by the pool.
+type PanicError struct {
+	Value     any    // The value passed to panic().
+	Stack     []byte // The stack of the panicking goroutine.
+	InputSize int    // The size of the offending input, or -1 if unknown.
+}
+
+// Implement the error interface for PanicError.
+func (e *PanicError) Error() string {
+	if e.InputSize < 0 {
+		return fmt.Sprintf("pool: task panicked: %v", e.Value)
+	}
+	return fmt.Sprintf("pool: task panicked on %d-byte input: %v", e.InputSize, e.Value)
+}
+
+// Unwrap gives access to the panic value when it was an error,
+// e.g. a runtime.Error from an out-of-range index.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// TimeoutError marks a task that did not finish within the TaskTimeout.
+type TimeoutError struct {
+	After     time.Duration // The timeout that expired.
+	InputSize int           // The size of the offending input, or -1 if unknown.
+}
+
+// Implement the error interface for TimeoutError.
+func (e *TimeoutError) Error() string {
+	if e.InputSize < 0 {
+		return fmt.Sprintf("pool: task timed out after %v", e.After)
+	}
+	return fmt.Sprintf("pool: task timed out after %v on %d-byte input", e.After, e.InputSize)
+}
+
+// Is makes errors.Is(err, ErrTaskTimeout) true for every TimeoutError.
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTaskTimeout
+}
+
+// Summary is what happened to the tasks of a pool.
+//
+// Panics and timeouts are isolated: they are counted here instead of
+// crashing the process or cancelling the pool, and the tasks that
+// caused them produce no result.
+type Summary struct {
+	Succeeded int
+	Panicked  int
+	TimedOut  int
+	Errors    []error // Every *PanicError and *TimeoutError, in the order they happened.
+
+	// Only counted by pools with a Retry policy.
+	Retried      int // Failed attempts that were scheduled for a retry.
+	DeadLettered int // Tasks that failed for good.
+}
+
+// sizeOf returns the size of a task input for error reports,
+// or -1 if the input has no obvious size.
+func sizeOf(v any) int {
+	switch v := v.(type) {
+	case []byte:
+		return len(v)
+	case string:
+		return len(v)
+	case interface{ Len() int }:
+		return v.Len()
+	default:
+		return -1
+	}
+}
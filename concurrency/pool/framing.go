@@ -0,0 +1,88 @@
+package pool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrTruncatedRecord is returned when the input ends in the middle of a
+// length-prefixed or fixed-size record.
+var ErrTruncatedRecord = errors.New("pool: input ends in the middle of a record")
+
+// --- Framing ---
+// Each function below returns a bufio.SplitFunc that cuts the input into
+// logical records, so that every task receives exactly one of them instead
+// of whatever happened to fit in a 1024-byte read.
+
+// Delimited splits the input on delim, e.g. '|' for "some-data-packet|".
+// The delimiter is not part of the record, and a final record without a
+// trailing delimiter is still delivered.
+func Delimited(delim byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.IndexByte(data, delim); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		// Request more data.
+		return 0, nil, nil
+	}
+}
+
+// Lines splits the input into lines, dropping the trailing "\n" or "\r\n".
+func Lines() bufio.SplitFunc {
+	return bufio.ScanLines
+}
+
+// lengthPrefixSize is the size of the big-endian uint32 in front of every
+// length-prefixed record.
+const lengthPrefixSize = 4
+
+// LengthPrefixed splits input where every record is preceded by its length
+// as a big-endian uint32. The prefix is not part of the record.
+func LengthPrefixed() bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if len(data) >= lengthPrefixSize {
+			size := int(binary.BigEndian.Uint32(data))
+			if end := lengthPrefixSize + size; len(data) >= end {
+				return end, data[lengthPrefixSize:end], nil
+			}
+		}
+		if atEOF {
+			if len(data) == 0 {
+				return 0, nil, nil
+			}
+			return 0, nil, ErrTruncatedRecord
+		}
+		// Request more data.
+		return 0, nil, nil
+	}
+}
+
+// FixedSize splits the input into records of exactly size bytes.
+// It panics if size is less than 1.
+func FixedSize(size int) bufio.SplitFunc {
+	if size < 1 {
+		panic("pool: record size must be at least 1")
+	}
+
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if len(data) >= size {
+			return size, data[:size], nil
+		}
+		if atEOF {
+			if len(data) == 0 {
+				return 0, nil, nil
+			}
+			return 0, nil, ErrTruncatedRecord
+		}
+		// Request more data.
+		return 0, nil, nil
+	}
+}
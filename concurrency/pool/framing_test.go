@@ -0,0 +1,130 @@
+package pool_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+// lengthPrefixed encodes records the way LengthPrefixed expects them.
+func lengthPrefixed(records ...string) string {
+	var b []byte
+	for _, r := range records {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(r)))
+		b = append(b, r...)
+	}
+	return string(b)
+}
+
+// framingCases are shared by the SplitFunc and the ReadContext tests.
+var framingCases = []struct {
+	name    string
+	split   bufio.SplitFunc
+	input   string
+	want    []string // The records before the error, if any.
+	wantErr error
+}{
+	{"lines", pool.Lines(), "a\nbb\r\nccc", []string{"a", "bb", "ccc"}, nil},
+	{"lines, empty ones", pool.Lines(), "a\n\nb\n", []string{"a", "", "b"}, nil},
+	{"lines, no input", pool.Lines(), "", nil, nil},
+
+	{"delimited", pool.Delimited('|'), "p1|p2|", []string{"p1", "p2"}, nil},
+	{"delimited, no final delimiter", pool.Delimited('|'), "p1|p2", []string{"p1", "p2"}, nil},
+	{"delimited, empty records", pool.Delimited('|'), "||x", []string{"", "", "x"}, nil},
+	{"delimited, no input", pool.Delimited('|'), "", nil, nil},
+
+	{"length-prefixed", pool.LengthPrefixed(), lengthPrefixed("abc", "", "a|b\nc"), []string{"abc", "", "a|b\nc"}, nil},
+	{"length-prefixed, truncated prefix", pool.LengthPrefixed(), lengthPrefixed("abc") + "\x00\x00", []string{"abc"}, pool.ErrTruncatedRecord},
+	{"length-prefixed, truncated record", pool.LengthPrefixed(), lengthPrefixed("abc", "hello")[:10], []string{"abc"}, pool.ErrTruncatedRecord},
+	{"length-prefixed, no input", pool.LengthPrefixed(), "", nil, nil},
+
+	{"fixed", pool.FixedSize(3), "abcdef", []string{"abc", "def"}, nil},
+	{"fixed, truncated record", pool.FixedSize(3), "abcdefg", []string{"abc", "def"}, pool.ErrTruncatedRecord},
+	{"fixed, no input", pool.FixedSize(3), "", nil, nil},
+}
+
+func TestFraming(t *testing.T) {
+	for _, tt := range framingCases {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte per Read: every SplitFunc has to ask for more data
+			// in the middle of its records.
+			sc := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(tt.input)))
+			sc.Split(tt.split)
+			var got []string
+			for sc.Scan() {
+				got = append(got, sc.Text())
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("records %q, want %q", got, tt.want)
+			}
+			if err := sc.Err(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Err() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadContextFraming(t *testing.T) {
+	for _, tt := range framingCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []string
+			task := func(_ context.Context, b []byte) (int, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, string(b))
+				return 1, nil
+			}
+
+			// A single worker keeps the records in order.
+			count, summary, err := pool.ReadContext(context.Background(), strings.NewReader(tt.input), 1, task,
+				pool.WithFraming(tt.split))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadContext: %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return // The pool aborts: the records before may or may not have run.
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("tasks got %q, want one per record: %q", got, tt.want)
+			}
+			if count != int64(len(tt.want)) || summary.Succeeded != len(tt.want) {
+				t.Errorf("count %d, %d succeeded; want %d tasks", count, summary.Succeeded, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestReadContextMaxRecordSize(t *testing.T) {
+	input := "short\n" + strings.Repeat("x", 100) + "\n"
+
+	_, _, err := pool.ReadContext(context.Background(), strings.NewReader(input), 1, countOne,
+		pool.WithFraming(pool.Lines()), pool.WithMaxRecordSize(50))
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("with a 50-byte limit: %v, want %v", err, bufio.ErrTooLong)
+	}
+
+	count, _, err := pool.ReadContext(context.Background(), strings.NewReader(input), 1, countOne,
+		pool.WithFraming(pool.Lines()), pool.WithMaxRecordSize(200))
+	if err != nil || count != 2 {
+		t.Errorf("with a 200-byte limit: %d records, %v; want 2, nil", count, err)
+	}
+}
+
+func TestFixedSizePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("FixedSize(0) did not panic")
+		}
+	}()
+	pool.FixedSize(0)
+}
@@ -0,0 +1,117 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"advanced-concepts/concurrency/internal/conc"
+)
+
+// run executes one task with panic isolation and, if configured,
+// under the per-task timeout.
+func (p *Pool[In, Out]) run(v In) (Out, error) {
+	if p.timeout <= 0 {
+		return p.call(p.ctx, v)
+	}
+
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
+	defer cancel()
+
+	// The task runs in its own goroutine so that the worker can walk away
+	// from it. The channel is buffered: an abandoned task can still send
+	// its result once it finally returns, and then exit.
+	type ret struct {
+		out Out
+		err error
+	}
+	done := make(chan ret, 1)
+	go func() {
+		out, err := p.call(ctx, v)
+		done <- ret{out, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.out, r.err
+
+	case <-ctx.Done():
+		// The task may have finished at the very same moment: prefer its result.
+		select {
+		case r := <-done:
+			return r.out, r.err
+		default:
+		}
+
+		var zero Out
+		if p.ctx.Err() != nil {
+			return zero, context.Cause(p.ctx) // The whole pool was cancelled.
+		}
+		return zero, &TimeoutError{After: p.timeout, InputSize: sizeOf(v)}
+	}
+}
+
+// call runs fn and turns a panic into a *PanicError.
+func (p *Pool[In, Out]) call(ctx context.Context, v In) (Out, error) {
+	return call(p.fn, ctx, v)
+}
+
+// call runs fn and turns a panic into a *PanicError. Every pool type runs
+// its tasks through it, so that a buggy task never takes the process down.
+func call[In, Out any](fn Func[In, Out], ctx context.Context, v In) (out Out, err error) {
+	if p := conc.Try(func() { out, err = fn(ctx, v) }); p != nil {
+		var zero Out
+		return zero, &PanicError{Value: p.Value, Stack: p.Stack, InputSize: sizeOf(v)}
+	}
+	return out, err
+}
+
+// isolated reports whether err is one of the failures that are recorded
+// in the Summary instead of cancelling the pool.
+func isolated(err error) bool {
+	var pe *PanicError
+	var te *TimeoutError
+	return errors.As(err, &pe) || errors.As(err, &te)
+}
+
+// stats is the Summary bookkeeping shared by every pool type.
+type stats struct {
+	statsMu sync.Mutex
+	summary Summary
+}
+
+// record counts the outcome of one task. With a Retry policy, every
+// failure that reaches it is a task that failed for good.
+func (s *stats) record(err error, retry bool) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	switch {
+	case err == nil:
+		s.summary.Succeeded++
+		return
+	case !isolated(err):
+		// Only reachable with a Retry policy: a plain error, dead-lettered.
+	case errors.Is(err, ErrTaskTimeout):
+		s.summary.TimedOut++
+		s.summary.Errors = append(s.summary.Errors, err)
+	default:
+		s.summary.Panicked++
+		s.summary.Errors = append(s.summary.Errors, err)
+	}
+
+	if retry {
+		s.summary.DeadLettered++
+	}
+}
+
+// Summary returns what has happened to the tasks so far.
+// Once Wait has returned, it is the final account.
+func (s *stats) Summary() Summary {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	sum := s.summary
+	sum.Errors = append([]error(nil), sum.Errors...)
+	return sum
+}
@@ -0,0 +1,195 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOverCapacity is returned when a task costs more than its Semaphore
+// can ever hold, since waiting for it would block forever.
+var ErrOverCapacity = errors.New("pool: task cost exceeds semaphore capacity")
+
+// TokenBucket limits how many tasks start per second.
+//
+// The bucket holds up to 'burst' tokens and refills at 'rate' tokens per
+// second; every task takes one token before it runs. Capping goroutines
+// (the pool size) caps concurrency; this caps throughput, which is what a
+// downstream quota is usually expressed in.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // Tokens per second.
+	burst  float64
+	tokens float64 // May go negative: tokens promised to waiting tasks.
+	last   time.Time
+}
+
+// NewTokenBucket returns a full bucket allowing perSecond tasks per second
+// with bursts of up to burst tasks. It panics if either is not positive.
+func NewTokenBucket(perSecond float64, burst int) *TokenBucket {
+	if perSecond <= 0 || burst < 1 {
+		panic("pool: token bucket needs a positive rate and burst")
+	}
+
+	return &TokenBucket{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	// Take the token now, even if it isn't there yet: the deficit tells
+	// us how long to sleep, and the next caller queues up behind us.
+	b.tokens--
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// We won't use the token after all: give it back.
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Coster is implemented by task inputs that declare their own cost
+// for a Weighted pool. Inputs that don't implement it cost 1.
+type Coster interface {
+	Cost() int64
+}
+
+// Semaphore is a weighted semaphore: tasks acquire as many units as they
+// cost, and waiters are served in FIFO order so a large task can't be
+// starved by a stream of small ones.
+type Semaphore struct {
+	mu      sync.Mutex
+	size    int64
+	cur     int64
+	waiters []semWaiter
+}
+
+type semWaiter struct {
+	n     int64
+	ready chan struct{} // Closed once the units have been granted.
+}
+
+// NewSemaphore returns a semaphore holding n units.
+func NewSemaphore(n int64) *Semaphore {
+	return &Semaphore{size: n}
+}
+
+// Acquire takes n units, blocking until they are available or ctx is done.
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if n > s.size {
+		s.mu.Unlock()
+		return ErrOverCapacity
+	}
+	if s.size-s.cur >= n && len(s.waiters) == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	w := semWaiter{n: n, ready: make(chan struct{})}
+	s.waiters = append(s.waiters, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		select {
+		case <-w.ready:
+			// Granted at the very same moment: keep the promise balanced.
+			s.cur -= n
+			s.notify()
+		default:
+			for i, other := range s.waiters {
+				if other.ready == w.ready {
+					s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+					break
+				}
+			}
+			// We may have been the head blocking smaller waiters.
+			s.notify()
+		}
+		return ctx.Err()
+	}
+}
+
+// Release gives n units back.
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur -= n
+	if s.cur < 0 {
+		panic("pool: semaphore released more than held")
+	}
+	s.notify()
+}
+
+// notify grants units to waiters in order, for as long as the head fits.
+// s.mu must be held.
+func (s *Semaphore) notify() {
+	for len(s.waiters) > 0 {
+		w := s.waiters[0]
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters = s.waiters[1:]
+		close(w.ready)
+	}
+}
+
+// RateLimit makes every task take a token from b before it runs.
+// Several pools may share one bucket to share one quota.
+func RateLimit(b *TokenBucket) Option {
+	return func(c *config) {
+		c.limiter = b
+	}
+}
+
+// Weighted makes every task acquire its cost from s before it runs and
+// release it when it finishes, so the total cost of the running tasks never
+// exceeds the capacity of s. The cost comes from the input's Cost method
+// if it implements Coster, and is 1 otherwise.
+func Weighted(s *Semaphore) Option {
+	return func(c *config) {
+		c.sem = s
+	}
+}
+
+// costOf returns the cost of a task input for a Weighted pool.
+func costOf(v any) int64 {
+	if c, ok := v.(Coster); ok {
+		return c.Cost()
+	}
+	return 1
+}
@@ -0,0 +1,227 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+func TestTokenBucketRate(t *testing.T) {
+	const (
+		rate  = 200 // Tokens per second: one every 5ms.
+		burst = 5
+		n     = 25
+	)
+	b := pool.NewTokenBucket(rate, burst)
+
+	// The burst goes through at once, the rest at the rate.
+	start := time.Now()
+	for i := 0; i < burst; i++ {
+		if err := b.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 4*time.Millisecond {
+		t.Errorf("the burst of %d took %v, want no wait", burst, d)
+	}
+	for i := burst; i < n; i++ {
+		if err := b.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d, want := time.Since(start), (n-burst)*time.Second/rate; d < want {
+		t.Errorf("%d tokens in %v, want at least %v past the burst", n, d, want)
+	}
+
+	// After an idle spell, the bucket refills to the burst and no more.
+	time.Sleep(time.Duration(2*burst) * time.Second / rate)
+	start = time.Now()
+	for i := 0; i <= burst; i++ {
+		b.Wait(context.Background())
+	}
+	if d := time.Since(start); d < 4*time.Millisecond {
+		t.Errorf("%d tokens after idling took %v, want the one past the burst to wait", burst+1, d)
+	}
+}
+
+func TestRateLimitedPoolThroughput(t *testing.T) {
+	const rate = 500
+	var ran atomic.Int64
+	p := pool.WithContext(context.Background(), 8, func(context.Context, int) (int, error) {
+		ran.Add(1)
+		return 0, nil
+	}, pool.RateLimit(pool.NewTokenBucket(rate, 1)))
+
+	start := time.Now()
+	submitAll(p, upTo(50))
+	drain(p.Results())
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	// 8 workers, but one task per 2ms: the first one is free.
+	if d, want := time.Since(start), 49*time.Second/rate; d < want || ran.Load() != 50 {
+		t.Errorf("%d tasks in %v, want 50 in at least %v", ran.Load(), d, want)
+	}
+}
+
+func TestTokenBucketCancel(t *testing.T) {
+	b := pool.NewTokenBucket(20, 1) // One token every 50ms.
+	start := time.Now()
+	b.Wait(context.Background()) // Empties the bucket.
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The cancelled waiter gave its token back: the next one is due 50ms
+	// after the start, not 100ms.
+	b.Wait(context.Background())
+	if d := time.Since(start); d > 90*time.Millisecond {
+		t.Errorf("next token after %v, want about 50ms", d)
+	}
+}
+
+// waiters starts one Acquire of s per cost, in order. Each reports its
+// index on granted once it got its units, or its error on errs.
+func waiters(ctx context.Context, s *pool.Semaphore, costs ...int64) (granted <-chan int, errs <-chan error) {
+	g, e := make(chan int, len(costs)), make(chan error, len(costs))
+	for i, n := range costs {
+		go func() {
+			if err := s.Acquire(ctx, n); err != nil {
+				e <- err
+				return
+			}
+			g <- i
+		}()
+		time.Sleep(5 * time.Millisecond) // Queue them up in order.
+	}
+	return g, e
+}
+
+// next returns the index of the next waiter granted its units, or -1 if
+// none is within 20ms.
+func next(granted <-chan int) int {
+	select {
+	case i := <-granted:
+		return i
+	case <-time.After(20 * time.Millisecond):
+		return -1
+	}
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	s := pool.NewSemaphore(3)
+	if err := s.Acquire(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Costs 2, 1, 1: the small ones must not overtake the big one.
+	granted, _ := waiters(context.Background(), s, 2, 1, 1)
+	var order []int
+
+	s.Release(1)
+	if i := next(granted); i != -1 {
+		t.Fatalf("waiter %d got in ahead of the head, which needs 2", i)
+	}
+	s.Release(1)
+	order = append(order, next(granted))
+	s.Release(2) // Both small waiters fit now.
+	order = append(order, next(granted), next(granted))
+	slices.Sort(order[1:]) // Granted together.
+
+	if !slices.Equal(order, []int{0, 1, 2}) {
+		t.Errorf("granted in order %v, want 0, 1, 2", order)
+	}
+}
+
+func TestSemaphoreCancel(t *testing.T) {
+	s := pool.NewSemaphore(2)
+	s.Acquire(context.Background(), 2)
+
+	// The head of the queue gives up: the waiter behind it, which fits,
+	// must not stay stuck behind it.
+	ctx, cancel := context.WithCancel(context.Background())
+	_, headErrs := waiters(ctx, s, 2)
+	granted, _ := waiters(context.Background(), s, 1)
+	s.Release(1)
+	if i := next(granted); i != -1 {
+		t.Fatal("the small waiter overtook the head")
+	}
+
+	cancel()
+	select {
+	case err := <-headErrs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Acquire() = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the cancelled waiter is still blocked")
+	}
+	if i := next(granted); i != 0 {
+		t.Error("the waiter behind the cancelled head wasn't granted")
+	}
+
+	// Everything is accounted for: 1 + 1 held, none left.
+	s.Release(2)
+	if err := s.Acquire(context.Background(), 2); err != nil {
+		t.Errorf("Acquire(2) on an empty semaphore: %v", err)
+	}
+}
+
+// cost is a task input declaring its own cost.
+type cost int64
+
+func (c cost) Cost() int64 { return int64(c) }
+
+func TestSemaphoreOverCapacity(t *testing.T) {
+	s := pool.NewSemaphore(3)
+	if err := s.Acquire(context.Background(), 4); !errors.Is(err, pool.ErrOverCapacity) {
+		t.Errorf("Acquire(4) = %v, want %v", err, pool.ErrOverCapacity)
+	}
+
+	// In a Weighted pool, the oversize task fails instead of hanging.
+	p := pool.WithContext(context.Background(), 2, func(_ context.Context, c cost) (int, error) {
+		return int(c), nil
+	}, pool.Weighted(s))
+	submitAll(p, []cost{1, 4, 2})
+	drain(p.Results())
+	if err := p.Wait(); !errors.Is(err, pool.ErrOverCapacity) {
+		t.Errorf("Wait() = %v, want %v", err, pool.ErrOverCapacity)
+	}
+}
+
+func TestWeightedPoolStaysUnderCapacity(t *testing.T) {
+	const capacity = 4
+	var mu sync.Mutex
+	var running, peak int64
+	p := pool.WithContext(context.Background(), 8, func(_ context.Context, c cost) (int, error) {
+		mu.Lock()
+		running += int64(c)
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running -= int64(c)
+		mu.Unlock()
+		return 0, nil
+	}, pool.Weighted(pool.NewSemaphore(capacity)))
+
+	submitAll(p, []cost{1, 3, 2, 4, 1, 1, 2, 3, 1, 2, 4, 1})
+	drain(p.Results())
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if peak > capacity {
+		t.Errorf("tasks costing %d ran at once, want at most %d", peak, capacity)
+	}
+}
@@ -0,0 +1,173 @@
+package pool
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements from an instrumented pool, see Instrument.
+// Methods are called from the workers and the producer concurrently,
+// so implementations must be safe for concurrent use and fast.
+type Metrics interface {
+	// QueueDepth is called with the number of queued tasks every time
+	// a task is submitted or picked up.
+	QueueDepth(n int)
+	// TaskLatency is called once per task with the time from Submit
+	// until the task finished, queueing included.
+	TaskLatency(d time.Duration)
+	// WorkerBusy is called once per task with the time a worker spent running it.
+	WorkerBusy(d time.Duration)
+	// WorkerIdle is called with the time a worker spent waiting for a task.
+	WorkerIdle(d time.Duration)
+	// ProducerBlocked is called once per Submit with the time it blocked.
+	ProducerBlocked(d time.Duration)
+}
+
+// Instrument makes the pool report to m.
+func Instrument(m Metrics) Option {
+	return func(c *config) {
+		c.metrics = m
+	}
+}
+
+// latencyBuckets are the upper bounds, in seconds, of the task latency
+// histogram: the Prometheus client's default buckets.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// MemoryMetrics is an in-memory Metrics that can print itself in the
+// Prometheus text format. It also is an http.Handler, so it can be
+// scraped from a local server:
+//
+//	m := pool.NewMemoryMetrics()
+//	go http.ListenAndServe("localhost:9090", m)
+//	p := pool.New(n, fn, pool.Instrument(m))
+type MemoryMetrics struct {
+	mu sync.Mutex
+
+	queueDepth    int
+	queueDepthMax int
+
+	buckets      []uint64 // Non-cumulative counts, one per latencyBuckets entry plus +Inf.
+	latencySum   time.Duration
+	latencyCount uint64
+
+	busy, idle, blocked time.Duration
+}
+
+// NewMemoryMetrics returns an empty MemoryMetrics.
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{
+		buckets: make([]uint64, len(latencyBuckets)+1),
+	}
+}
+
+// QueueDepth implements Metrics.
+func (m *MemoryMetrics) QueueDepth(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queueDepth = n
+	m.queueDepthMax = max(m.queueDepthMax, n)
+}
+
+// TaskLatency implements Metrics.
+func (m *MemoryMetrics) TaskLatency(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := 0
+	for i < len(latencyBuckets) && d.Seconds() > latencyBuckets[i] {
+		i++
+	}
+	m.buckets[i]++
+	m.latencySum += d
+	m.latencyCount++
+}
+
+// WorkerBusy implements Metrics.
+func (m *MemoryMetrics) WorkerBusy(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.busy += d
+}
+
+// WorkerIdle implements Metrics.
+func (m *MemoryMetrics) WorkerIdle(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.idle += d
+}
+
+// ProducerBlocked implements Metrics.
+func (m *MemoryMetrics) ProducerBlocked(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blocked += d
+}
+
+// Utilisation returns the share of worker time spent running tasks,
+// between 0 and 1, or 0 if nothing was measured yet.
+func (m *MemoryMetrics) Utilisation() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.busy+m.idle == 0 {
+		return 0
+	}
+	return m.busy.Seconds() / (m.busy + m.idle).Seconds()
+}
+
+// WritePrometheus writes every metric to w in the Prometheus text format.
+func (m *MemoryMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Errors are sticky: after the first failed write, the rest is skipped.
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	metric := func(name, kind, help string) {
+		printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("pool_queue_depth", "gauge", "Tasks waiting in the queue.")
+	printf("pool_queue_depth %d\n", m.queueDepth)
+	metric("pool_queue_depth_max", "gauge", "Largest queue depth seen.")
+	printf("pool_queue_depth_max %d\n", m.queueDepthMax)
+
+	metric("pool_task_latency_seconds", "histogram", "Time from Submit until the task finished.")
+	var cumulative uint64
+	for i, le := range latencyBuckets {
+		cumulative += m.buckets[i]
+		printf("pool_task_latency_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	printf("pool_task_latency_seconds_bucket{le=\"+Inf\"} %d\n", m.latencyCount)
+	printf("pool_task_latency_seconds_sum %g\n", m.latencySum.Seconds())
+	printf("pool_task_latency_seconds_count %d\n", m.latencyCount)
+
+	metric("pool_worker_busy_seconds_total", "counter", "Time workers spent running tasks.")
+	printf("pool_worker_busy_seconds_total %g\n", m.busy.Seconds())
+	metric("pool_worker_idle_seconds_total", "counter", "Time workers spent waiting for tasks.")
+	printf("pool_worker_idle_seconds_total %g\n", m.idle.Seconds())
+	metric("pool_producer_blocked_seconds_total", "counter", "Time Submit spent blocked on a full queue.")
+	printf("pool_producer_blocked_seconds_total %g\n", m.blocked.Seconds())
+
+	return err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format. If the
+// response can't be written in full, it is aborted: a scraper must not
+// mistake the part that got through for every metric.
+func (m *MemoryMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.WritePrometheus(w); err != nil {
+		panic(http.ErrAbortHandler) // net/http drops the connection.
+	}
+}
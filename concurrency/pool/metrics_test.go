@@ -0,0 +1,158 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+// golden is what WritePrometheus prints for the measurements of measured.
+const golden = `# HELP pool_queue_depth Tasks waiting in the queue.
+# TYPE pool_queue_depth gauge
+pool_queue_depth 1
+# HELP pool_queue_depth_max Largest queue depth seen.
+# TYPE pool_queue_depth_max gauge
+pool_queue_depth_max 3
+# HELP pool_task_latency_seconds Time from Submit until the task finished.
+# TYPE pool_task_latency_seconds histogram
+pool_task_latency_seconds_bucket{le="0.005"} 2
+pool_task_latency_seconds_bucket{le="0.01"} 2
+pool_task_latency_seconds_bucket{le="0.025"} 3
+pool_task_latency_seconds_bucket{le="0.05"} 3
+pool_task_latency_seconds_bucket{le="0.1"} 3
+pool_task_latency_seconds_bucket{le="0.25"} 3
+pool_task_latency_seconds_bucket{le="0.5"} 3
+pool_task_latency_seconds_bucket{le="1"} 3
+pool_task_latency_seconds_bucket{le="2.5"} 4
+pool_task_latency_seconds_bucket{le="5"} 4
+pool_task_latency_seconds_bucket{le="10"} 4
+pool_task_latency_seconds_bucket{le="+Inf"} 5
+pool_task_latency_seconds_sum 32.028
+pool_task_latency_seconds_count 5
+# HELP pool_worker_busy_seconds_total Time workers spent running tasks.
+# TYPE pool_worker_busy_seconds_total counter
+pool_worker_busy_seconds_total 1.5
+# HELP pool_worker_idle_seconds_total Time workers spent waiting for tasks.
+# TYPE pool_worker_idle_seconds_total counter
+pool_worker_idle_seconds_total 0.5
+# HELP pool_producer_blocked_seconds_total Time Submit spent blocked on a full queue.
+# TYPE pool_producer_blocked_seconds_total counter
+pool_producer_blocked_seconds_total 0.25
+`
+
+// measured returns a MemoryMetrics fed by hand with the measurements
+// behind golden.
+func measured() *pool.MemoryMetrics {
+	m := pool.NewMemoryMetrics()
+	m.QueueDepth(3)
+	m.QueueDepth(1)
+
+	// A bucket's bound is inclusive: 5ms counts as le="0.005".
+	for _, d := range []time.Duration{
+		3 * time.Millisecond,
+		5 * time.Millisecond,
+		20 * time.Millisecond,
+		2 * time.Second,
+		30 * time.Second, // Only in +Inf.
+	} {
+		m.TaskLatency(d)
+	}
+
+	m.WorkerBusy(time.Second)
+	m.WorkerBusy(500 * time.Millisecond)
+	m.WorkerIdle(500 * time.Millisecond)
+	m.ProducerBlocked(250 * time.Millisecond)
+	return m
+}
+
+func TestWritePrometheus(t *testing.T) {
+	m := measured()
+
+	var b strings.Builder
+	if err := m.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != golden {
+		t.Errorf("WritePrometheus wrote:\n%s\nwant:\n%s", got, golden)
+	}
+	if u := m.Utilisation(); u != 0.75 {
+		t.Errorf("Utilisation() = %v, want 0.75", u)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	rec := httptest.NewRecorder()
+	measured().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != golden {
+		t.Errorf("%d %q, want 200 and the golden metrics", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type %q, want the Prometheus text format", ct)
+	}
+}
+
+// brokenWriter fails every write after the first n bytes, like a
+// connection whose client has gone away.
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+	n int
+}
+
+var errGone = errors.New("client gone")
+
+func (w *brokenWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		w.ResponseRecorder.Write(p[:w.n])
+		return w.n, errGone
+	}
+	w.n -= len(p)
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestWritePrometheusError(t *testing.T) {
+	w := &brokenWriter{ResponseRecorder: httptest.NewRecorder(), n: 100}
+	if err := measured().WritePrometheus(w); !errors.Is(err, errGone) {
+		t.Errorf("WritePrometheus() = %v, want %v", err, errGone)
+	}
+	if n := w.Body.Len(); n != 100 {
+		t.Errorf("%d bytes written, want nothing after the failure", n)
+	}
+
+	// ServeHTTP aborts the response rather than leave it truncated.
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("ServeHTTP panicked with %v, want http.ErrAbortHandler", r)
+		}
+	}()
+	measured().ServeHTTP(&brokenWriter{ResponseRecorder: httptest.NewRecorder(), n: 100}, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+}
+
+func TestInstrument(t *testing.T) {
+	m := pool.NewMemoryMetrics()
+	p := pool.WithContext(context.Background(), 2, func(_ context.Context, v int) (int, error) {
+		return v, nil
+	}, pool.Instrument(m))
+	submitAll(p, upTo(10))
+	drain(p.Results())
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	m.WritePrometheus(&b)
+	for _, want := range []string{
+		"pool_task_latency_seconds_count 10\n",
+		`pool_task_latency_seconds_bucket{le="+Inf"} 10` + "\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics are missing %q:\n%s", want, b.String())
+		}
+	}
+}
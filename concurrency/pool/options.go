@@ -0,0 +1,48 @@
+package pool
+
+import (
+	"time"
+)
+
+// Option configures a Pool created by New or WithContext.
+type Option func(*config)
+
+type config struct {
+	window  int           // > 0 means ordered delivery with that many tasks in flight.
+	timeout time.Duration // > 0 means a deadline per task.
+
+	autoscale *AutoscaleConfig
+	metrics   Metrics
+	retry     *RetryPolicy
+	limiter   *TokenBucket
+	sem       *Semaphore
+}
+
+// Ordered makes the pool deliver results in the order the tasks were
+// submitted, instead of in the order they happen to finish.
+//
+// At most window tasks may be in flight, counting from the oldest result
+// that has not been delivered yet: when a slow task holds up the head, the
+// reorder buffer fills up and Submit blocks until that task finishes.
+// A window smaller than the pool size leaves some workers idle.
+// It panics if window is less than 1.
+func Ordered(window int) Option {
+	if window < 1 {
+		panic("pool: ordered window must be at least 1")
+	}
+
+	return func(c *config) {
+		c.window = window
+	}
+}
+
+// TaskTimeout gives every task at most d to finish. A task that takes longer
+// is reported as a *TimeoutError in the Summary and the worker moves on to
+// the next task; the context the task received is cancelled, but the pool
+// cannot stop a task that ignores it, so such a task keeps running in the
+// background until it returns on its own.
+func TaskTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
@@ -0,0 +1,48 @@
+package pool
+
+// item is a result tagged with the sequence number of the task it came from.
+type item[Out any] struct {
+	seq  uint64
+	v    Out
+	skip bool // The task panicked or timed out: there is nothing to deliver.
+}
+
+// reorderLoop is the reorder buffer of an Ordered pool.
+//
+// Workers finish in any order; results that arrive early are parked in
+// 'pending' until every result before them has been delivered. The map
+// can never hold more than the window size, because Submit needs a window
+// slot for every task and a slot is only given back here, once the result
+// has actually been sent on 'results' (or skipped, for a failed task).
+func (p *Pool[In, Out]) reorderLoop() {
+	defer close(p.reorderDone)
+
+	pending := make(map[uint64]item[Out])
+	var next uint64
+
+	// Keep receiving until the workers are gone, even after cancellation,
+	// so that no worker is ever left blocked on 'reorder'.
+	for it := range p.reorder {
+		pending[it.seq] = it
+
+		// Deliver the longest run of consecutive results we now have.
+		for p.ctx.Err() == nil {
+			head, ok := pending[next]
+			if !ok {
+				break // The head is still running.
+			}
+
+			if !head.skip {
+				select {
+				case p.results <- head.v:
+				case <-p.ctx.Done():
+					continue // The loop condition ends the run.
+				}
+			}
+
+			delete(pending, next)
+			next++
+			<-p.window // Give the slot back: the producer may submit again.
+		}
+	}
+}
@@ -0,0 +1,467 @@
+// Package pool is the worker pool from concurrency/worker-pool-pattern
+// promoted into an importable, generic package.
+//
+// The shape is exactly the one of the book's read():
+//   - a fixed number of workers is started up front,
+//   - they all range over one shared buffered channel,
+//   - closing that channel is the signal for them to exit.
+//
+// The only difference is that the input and the output are no longer
+// hard-wired to []byte and an int64 sum: results are delivered on a
+// channel and the caller decides how to aggregate them.
+//
+// On top of that, a pool created with WithContext can be cancelled and
+// behaves like an errgroup: the first task error cancels the remaining work,
+// and every worker exits on every return path.
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Func is a task that can observe cancellation and can fail.
+// The context it receives is cancelled as soon as the pool is aborted.
+type Func[In, Out any] func(ctx context.Context, v In) (Out, error)
+
+// task is a submitted value tagged with its position in the input.
+type task[In any] struct {
+	seq     uint64
+	v       In
+	queued  time.Time // When it was submitted; only set when someone measures.
+	attempt int       // 1 for the first run, incremented by every retry.
+}
+
+// Pool runs fn over every submitted value using a fixed number of workers.
+//
+// Usage follows the same three steps as read():
+//
+//	p := pool.New(n, fn)
+//	go func() {
+//		defer p.Close()        // "close(ch)": no more tasks.
+//		for ... { p.Submit(v) } // the producer.
+//	}()
+//	for out := range p.Results() { ... } // the consumer.
+//	err := p.Wait()                      // "wg.Wait()".
+//
+// Results must be drained: a worker blocks until its result is received
+// (or until the pool is cancelled).
+type Pool[In, Out any] struct {
+	fn      Func[In, Out]
+	timeout time.Duration
+	settled func(In) // nil, or told when a task won't run again.
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	tasks   chan task[In]
+	results chan Out
+	seq     atomic.Uint64 // The sequence number of the next submitted task.
+
+	// Only used by Ordered pools: workers send to 'reorder', the
+	// reorder goroutine sends to 'results', 'window' limits tasks in flight.
+	reorder     chan item[Out]
+	window      chan struct{}
+	reorderDone chan struct{}
+
+	// Pool size. Autoscaling pools change it at runtime: 'retire'
+	// stops an idle worker, 'excess' counts busy workers asked to stop.
+	size   atomic.Int64
+	excess atomic.Int64
+	retire chan struct{}
+	scaler *scaler
+
+	metrics Metrics // nil unless instrumented.
+
+	limiter *TokenBucket // nil unless rate limited.
+	sem     *Semaphore   // nil unless weighted.
+
+	// Only used by Retry pools. 'outstanding' counts tasks that were
+	// submitted and are not settled yet: while a failed task waits for
+	// its retry, the tasks channel must stay open even after Close.
+	retry       *RetryPolicy
+	outMu       sync.Mutex
+	outstanding int
+	closing     bool
+
+	deadLetters chan DeadLetter[In]
+	dlMu        sync.Mutex
+	dlCond      *sync.Cond
+	dlQueue     []DeadLetter[In]
+	dlDone      bool // The workers are gone: nothing more will be queued.
+
+	// Counters for the autoscaler.
+	completed atomic.Int64 // Tasks that ran, whatever the outcome.
+	dequeued  atomic.Int64 // Tasks picked up by a worker.
+	waited    atomic.Int64 // Total nanoseconds tasks spent in the queue.
+
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closed    chan struct{} // Closed by Close.
+	done      chan struct{} // Closed once every worker has returned.
+	err       error         // The first error; only read after 'done' is closed.
+
+	stats
+}
+
+// New creates a pool of n workers that cannot fail and starts them immediately.
+// It panics if n is less than 1, since such a pool could never make progress.
+func New[In, Out any](n int, fn func(In) Out, opts ...Option) *Pool[In, Out] {
+	return WithContext(context.Background(), n, func(_ context.Context, v In) (Out, error) {
+		return fn(v), nil
+	}, opts...)
+}
+
+// WithContext creates a pool of n workers bound to ctx.
+//
+// The pool is cancelled when ctx is done, when a task returns an error or
+// when Abort is called, whichever happens first. Once cancelled, queued tasks
+// are dropped, Submit fails and the workers exit; Wait reports the cause.
+func WithContext[In, Out any](ctx context.Context, n int, fn Func[In, Out], opts ...Option) *Pool[In, Out] {
+	return withSettled(ctx, n, fn, nil, opts...)
+}
+
+// withSettled is WithContext with a hook that is called once per task, as
+// soon as the pool is done with it: the task succeeded, failed for good or
+// cancelled the pool. Tasks dropped by a cancelled pool are never settled.
+func withSettled[In, Out any](ctx context.Context, n int, fn Func[In, Out], settled func(In), opts ...Option) *Pool[In, Out] {
+	if n < 1 {
+		panic("pool: n must be at least 1")
+	}
+
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// An autoscaling pool needs room to queue up to Max tasks,
+	// otherwise its queue latency is hidden in a blocked producer.
+	buffer := n
+	if cfg.autoscale != nil {
+		n = min(max(n, cfg.autoscale.Min), cfg.autoscale.Max)
+		buffer = cfg.autoscale.Max
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	p := &Pool[In, Out]{
+		fn:      fn,
+		timeout: cfg.timeout,
+		settled: settled,
+		metrics: cfg.metrics,
+		retry:   cfg.retry,
+		limiter: cfg.limiter,
+		sem:     cfg.sem,
+		ctx:     ctx,
+		cancel:  cancel,
+		// Same sizing as read(): a buffer equal to the pool size keeps
+		// the producer from blocking while the workers are busy.
+		tasks:   make(chan task[In], buffer),
+		results: make(chan Out, buffer),
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+
+		deadLetters: make(chan DeadLetter[In]),
+	}
+
+	if p.retry != nil {
+		p.dlCond = sync.NewCond(&p.dlMu)
+		go p.forwardDeadLetters()
+	} else {
+		close(p.deadLetters) // Nothing will ever be sent on it.
+	}
+
+	if cfg.window > 0 {
+		p.reorder = make(chan item[Out], n)
+		p.window = make(chan struct{}, cfg.window)
+		p.reorderDone = make(chan struct{})
+		go p.reorderLoop()
+	}
+
+	if cfg.autoscale != nil {
+		p.retire = make(chan struct{})
+		p.scaler = &scaler{cfg: *cfg.autoscale, start: time.Now()}
+	}
+
+	// Add 'n' workers to the wait group *before* starting them.
+	p.grow(n)
+
+	if p.scaler != nil {
+		p.wg.Add(1)
+		go p.autoscale()
+	}
+
+	// Once every worker is gone nobody can send a result any more,
+	// so this is the one place where it is safe to close 'results'.
+	go func() {
+		p.wg.Wait()
+
+		// In ordered mode the workers don't own 'results':
+		// let the reorder goroutine flush what it holds first.
+		if p.reorder != nil {
+			close(p.reorder)
+			<-p.reorderDone
+		}
+
+		// Let the dead letter goroutine close its channel once it is empty.
+		if p.retry != nil {
+			p.dlMu.Lock()
+			p.dlDone = true
+			p.dlMu.Unlock()
+			p.dlCond.Broadcast()
+		}
+
+		// Record why we stopped *before* releasing the context,
+		// otherwise every run would end with context.Canceled.
+		if p.ctx.Err() != nil {
+			p.err = context.Cause(p.ctx)
+		}
+		p.cancel(nil)
+
+		close(p.results)
+		close(p.done)
+	}()
+
+	return p
+}
+
+// worker receives tasks until the tasks channel is closed
+// or the pool is cancelled.
+func (p *Pool[In, Out]) worker() {
+	defer p.wg.Done()
+	retired := false
+	defer func() {
+		if !retired {
+			p.size.Add(-1)
+		}
+	}()
+
+	for {
+		// 'select' picks randomly among ready cases, so check for
+		// cancellation first: once aborted, no new task is started.
+		if p.ctx.Err() != nil || p.retiring() {
+			return
+		}
+
+		var idleSince time.Time
+		if p.metrics != nil {
+			idleSince = time.Now()
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+
+		case <-p.retire: // nil, so never ready, unless autoscaling.
+			retired = true // shrink has already taken us off the size.
+			return
+
+		case t, open := <-p.tasks:
+			if !open {
+				if p.metrics != nil {
+					p.metrics.WorkerIdle(time.Since(idleSince))
+				}
+				return
+			}
+			if !t.queued.IsZero() {
+				p.dequeued.Add(1)
+				p.waited.Add(int64(time.Since(t.queued)))
+			}
+
+			cost, err := p.admit(t.v)
+			if err != nil {
+				if p.ctx.Err() != nil {
+					return
+				}
+				var zero Out
+				if !p.settle(t, zero, err) {
+					return
+				}
+				continue
+			}
+
+			var started time.Time
+			if p.metrics != nil {
+				started = time.Now()
+				p.metrics.WorkerIdle(started.Sub(idleSince))
+				p.metrics.QueueDepth(len(p.tasks))
+			}
+
+			out, err := p.run(t.v)
+			if p.sem != nil {
+				p.sem.Release(cost)
+			}
+			p.completed.Add(1)
+			if p.metrics != nil {
+				p.metrics.WorkerBusy(time.Since(started))
+				p.metrics.TaskLatency(time.Since(t.queued))
+			}
+
+			if !p.settle(t, out, err) {
+				return
+			}
+		}
+	}
+}
+
+// admit waits until t may run under the pool's rate limit and semaphore,
+// and returns the cost it acquired from the semaphore.
+func (p *Pool[In, Out]) admit(v In) (int64, error) {
+	if p.limiter != nil {
+		if err := p.limiter.Wait(p.ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	if p.sem == nil {
+		return 0, nil
+	}
+	cost := costOf(v)
+	return cost, p.sem.Acquire(p.ctx, cost)
+}
+
+// settle decides what happens to a task that just ran and reports
+// whether the worker should carry on.
+func (p *Pool[In, Out]) settle(t task[In], out Out, err error) bool {
+	if err != nil && p.retry != nil {
+		if t.attempt < p.retry.MaxAttempts && p.retry.Retryable(err) {
+			p.requeue(t) // Still outstanding: it will come back.
+			return true
+		}
+		p.deadLetter(t, err)
+	} else if err != nil && !isolated(err) {
+		// errgroup-style: the first failure cancels everything else.
+		p.release(t.v)
+		p.Abort(err)
+		return false
+	}
+
+	p.release(t.v)
+	p.record(err, p.retry != nil)
+	if p.retry != nil {
+		p.finish()
+	}
+
+	// A failed task has no result, but an ordered pool
+	// still needs to hear about it to move past its slot.
+	return p.emit(item[Out]{seq: t.seq, v: out, skip: err != nil})
+}
+
+// release tells the settled hook, if any, that v won't run again.
+func (p *Pool[In, Out]) release(v In) {
+	if p.settled != nil {
+		p.settled(v)
+	}
+}
+
+// Submit hands v to the workers. It blocks while the buffer is full and
+// returns the cancellation cause if the pool is cancelled in the meantime.
+// Like a send on a channel, it must not be called after Close.
+func (p *Pool[In, Out]) Submit(v In) error {
+	// Don't let a ready buffer win the race against a cancelled pool.
+	if p.ctx.Err() != nil {
+		return context.Cause(p.ctx)
+	}
+
+	var start time.Time
+	if p.scaler != nil || p.metrics != nil {
+		start = time.Now()
+	}
+
+	// Ordered pools first take a slot in the window; the reorder
+	// goroutine gives it back once the result has been delivered.
+	if p.window != nil {
+		select {
+		case p.window <- struct{}{}:
+		case <-p.ctx.Done():
+			return context.Cause(p.ctx)
+		}
+	}
+
+	if p.retry != nil {
+		p.track()
+	}
+
+	t := task[In]{seq: p.seq.Add(1) - 1, v: v, queued: start, attempt: 1}
+	select {
+	case p.tasks <- t:
+		if p.metrics != nil {
+			p.metrics.ProducerBlocked(time.Since(start))
+			p.metrics.QueueDepth(len(p.tasks))
+		}
+		return nil
+	case <-p.ctx.Done():
+		return context.Cause(p.ctx)
+	}
+}
+
+// emit hands a finished task to whoever delivers results: the consumer
+// directly, or the reorder goroutine for ordered pools. It reports false
+// if the pool was cancelled before the result could be handed over.
+func (p *Pool[In, Out]) emit(it item[Out]) bool {
+	// Never block forever on a consumer that stopped reading
+	// because the pool was cancelled.
+	if p.reorder != nil {
+		select {
+		case p.reorder <- it:
+			return true
+		case <-p.ctx.Done():
+			return false
+		}
+	}
+
+	if it.skip {
+		return true
+	}
+
+	select {
+	case p.results <- it.v:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// Abort cancels the pool with err as the cause, as if a task had failed with it.
+// Only the first cause is kept; later calls have no effect.
+func (p *Pool[In, Out]) Abort(err error) {
+	p.cancel(err)
+}
+
+// Results returns the channel the workers send their outputs on.
+// It is closed once every worker has exited.
+func (p *Pool[In, Out]) Results() <-chan Out {
+	return p.results
+}
+
+// Close signals that no more tasks will be submitted.
+// Workers finish what is already queued (including the retries of a Retry
+// pool) and then exit. It is safe to call Close more than once.
+func (p *Pool[In, Out]) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+
+		if p.retry != nil {
+			// A failed task may still come back: leave closing the
+			// tasks channel to the last task that settles.
+			p.outMu.Lock()
+			defer p.outMu.Unlock()
+			p.closing = true
+			if p.outstanding == 0 {
+				close(p.tasks)
+			}
+			return
+		}
+
+		close(p.tasks)
+	})
+}
+
+// Wait blocks until every worker has exited, i.e. until Close has been
+// called and every queued task has been processed, or until the pool has
+// been cancelled. It returns the first error, or nil if all tasks succeeded.
+func (p *Pool[In, Out]) Wait() error {
+	<-p.done
+	return p.err
+}
@@ -0,0 +1,231 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+// submitAll submits every value of vs from its own goroutine, the way
+// read() does, and closes the pool once they are all in.
+func submitAll[In any](p interface {
+	Submit(In) error
+	Close()
+}, vs []In) {
+	go func() {
+		defer p.Close()
+		for _, v := range vs {
+			if p.Submit(v) != nil {
+				return
+			}
+		}
+	}()
+}
+
+// drain collects every result of ch.
+func drain[T any](ch <-chan T) []T {
+	var vs []T
+	for v := range ch {
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+// upTo returns 0, 1, ..., n-1.
+func upTo(n int) []int {
+	vs := make([]int, n)
+	for i := range vs {
+		vs[i] = i
+	}
+	return vs
+}
+
+func TestPool(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name    string
+		workers int
+		opts    []pool.Option
+		fn      pool.Func[int, int]
+		inputs  []int
+		want    []int // Sorted, unless the pool is ordered.
+		ordered bool
+		wantErr error
+	}{
+		{
+			name:    "one worker",
+			workers: 1,
+			fn:      func(_ context.Context, v int) (int, error) { return v * v, nil },
+			inputs:  upTo(5),
+			want:    []int{0, 1, 4, 9, 16},
+		},
+		{
+			name:    "more workers than tasks",
+			workers: 16,
+			fn:      func(_ context.Context, v int) (int, error) { return v + 1, nil },
+			inputs:  upTo(3),
+			want:    []int{1, 2, 3},
+		},
+		{
+			name:    "no tasks",
+			workers: 4,
+			fn:      func(_ context.Context, v int) (int, error) { return v, nil },
+		},
+		{
+			name:    "ordered, with a slow head",
+			workers: 4,
+			opts:    []pool.Option{pool.Ordered(4)},
+			fn: func(_ context.Context, v int) (int, error) {
+				if v == 0 {
+					time.Sleep(20 * time.Millisecond)
+				}
+				return v, nil
+			},
+			inputs:  upTo(10),
+			want:    upTo(10),
+			ordered: true,
+		},
+		{
+			name:    "a task error cancels the pool",
+			workers: 2,
+			fn: func(ctx context.Context, v int) (int, error) {
+				if v == 3 {
+					return 0, errBoom
+				}
+				return v, nil
+			},
+			inputs:  upTo(100),
+			wantErr: errBoom,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := pool.WithContext(context.Background(), tt.workers, tt.fn, tt.opts...)
+			submitAll(p, tt.inputs)
+
+			got := drain(p.Results())
+			err := p.Wait()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Wait() = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return // Which results made it before the error is up to the scheduler.
+			}
+
+			if !tt.ordered {
+				slices.Sort(got)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoolAbortDropsQueuedTasks(t *testing.T) {
+	errStop := errors.New("stop")
+	release := make(chan struct{})
+	p := pool.WithContext(context.Background(), 1, func(ctx context.Context, v int) (int, error) {
+		<-release
+		return v, nil
+	})
+	submitAll(p, upTo(1000))
+
+	p.Abort(errStop)
+	close(release)
+
+	if got := len(drain(p.Results())); got > 1 {
+		t.Errorf("%d results after Abort, want at most the task that was running", got)
+	}
+	if err := p.Wait(); !errors.Is(err, errStop) {
+		t.Errorf("Wait() = %v, want %v", err, errStop)
+	}
+}
+
+func TestPoolTaskTimeout(t *testing.T) {
+	p := pool.WithContext(context.Background(), 2, func(ctx context.Context, d time.Duration) (time.Duration, error) {
+		time.Sleep(d) // Ignores ctx on purpose.
+		return d, nil
+	}, pool.TaskTimeout(20*time.Millisecond))
+	submitAll(p, []time.Duration{0, 100 * time.Millisecond, 0})
+
+	got := drain(p.Results())
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+
+	s := p.Summary()
+	if len(got) != 2 || s.Succeeded != 2 || s.TimedOut != 1 {
+		t.Errorf("results %v, summary %+v; want 2 results and 1 timeout", got, s)
+	}
+	if len(s.Errors) != 1 || !errors.Is(s.Errors[0], pool.ErrTaskTimeout) {
+		t.Errorf("Errors = %v, want one ErrTaskTimeout", s.Errors)
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int64
+	}{
+		{"empty", "", 0},
+		{"less than a chunk", "abc", 1},
+		{"exactly one chunk", strings.Repeat("x", pool.ChunkSize), 1},
+		{"a chunk and a bit", strings.Repeat("x", pool.ChunkSize+1), 2},
+		{"many chunks", strings.Repeat("x", 10*pool.ChunkSize), 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pool.Read(strings.NewReader(tt.input), func([]byte) int { return 1 }, 4)
+			if err != nil || got != tt.want {
+				t.Errorf("Read() = %d, %v; want %d, nil", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// benchInput is the worker-pool-pattern example's input: 200 packets, about 4KB.
+var benchInput = strings.Repeat("some-data-packet|", 200)
+
+// BenchmarkRead measures what Read itself costs per call, with a task that
+// does nothing. The example's CPU- and I/O-bound workloads are benchmarked
+// against the book's read() in concurrency/worker-pool-pattern.
+func BenchmarkRead(b *testing.B) {
+	for _, workers := range []int{1, 4, 50} {
+		b.Run(fmt.Sprintf("%d-workers", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := pool.Read(strings.NewReader(benchInput), func([]byte) int { return 1 }, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPool(b *testing.B) {
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("%d-workers", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			p := pool.New(workers, func(v int) int { return v })
+			go func() {
+				defer p.Close()
+				for i := 0; i < b.N; i++ {
+					p.Submit(i)
+				}
+			}()
+			for range p.Results() {
+			}
+			p.Wait()
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// PriorityConfig configures a PriorityPool.
+type PriorityConfig struct {
+	// Levels is the number of priority levels; priorities go from 0, the
+	// most urgent, to Levels-1. It defaults to 3.
+	Levels int
+
+	// StarvationLimit is how many tasks may be taken from higher levels in
+	// a row while a lower level is waiting. After that, the lower level is
+	// served once, so every class keeps making progress under sustained
+	// load. It defaults to 8.
+	StarvationLimit int
+
+	// Weights gives tenants a larger share of their level: a tenant of
+	// weight 3 gets three tasks per round where others get one.
+	// Tenants that are not listed have weight 1.
+	Weights map[string]int
+
+	// MaxQueued bounds the number of queued tasks; Submit blocks while
+	// the queue is full. 0 means unbounded.
+	MaxQueued int
+}
+
+// PriorityPool is a worker pool that replaces the single FIFO channel of
+// Pool with priority levels and per-tenant fair queuing:
+//   - workers always take a task from the most urgent non-empty level,
+//     except that a level that has been passed over StarvationLimit times
+//     in a row is served once;
+//   - inside a level, tenants (the submitters) are served in weighted
+//     round robin, so a tenant flooding the level can't push the others back.
+//
+// Like a WithContext Pool, the first task error cancels the pool, while
+// panics are isolated and counted in the Summary.
+type PriorityPool[In, Out any] struct {
+	fn  Func[In, Out]
+	cfg PriorityConfig
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond // Signalled when a task is queued or removed, or on close/cancel.
+	levels  []*level[In]
+	skipped []int // Per level: tasks served from higher levels while it waited.
+	queued  int
+	closed  bool
+
+	results chan Out
+	wg      sync.WaitGroup
+	done    chan struct{}
+	err     error
+
+	stats
+}
+
+// level is one priority class: a queue per tenant, served round robin.
+type level[In any] struct {
+	queues map[string][]In
+	ring   []string // Tenants with queued tasks, in service order.
+	cur    int      // Index in ring of the tenant being served.
+	credit int      // Tasks the current tenant may still take this round.
+}
+
+// NewPriority creates a PriorityPool of n workers bound to ctx.
+// It panics if n is less than 1.
+func NewPriority[In, Out any](ctx context.Context, n int, fn Func[In, Out], cfg PriorityConfig) *PriorityPool[In, Out] {
+	if n < 1 {
+		panic("pool: n must be at least 1")
+	}
+	if cfg.Levels < 1 {
+		cfg.Levels = 3
+	}
+	if cfg.StarvationLimit < 1 {
+		cfg.StarvationLimit = 8
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	p := &PriorityPool[In, Out]{
+		fn:      fn,
+		cfg:     cfg,
+		ctx:     ctx,
+		cancel:  cancel,
+		levels:  make([]*level[In], cfg.Levels),
+		skipped: make([]int, cfg.Levels),
+		results: make(chan Out, n),
+		done:    make(chan struct{}),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for i := range p.levels {
+		p.levels[i] = &level[In]{queues: make(map[string][]In)}
+	}
+
+	// A sync.Cond can't wait on a channel: wake everybody up on cancellation.
+	stop := context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.cond.Broadcast()
+	})
+
+	p.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go p.worker()
+	}
+
+	go func() {
+		p.wg.Wait()
+		stop()
+
+		if p.ctx.Err() != nil {
+			p.err = context.Cause(p.ctx)
+		}
+		p.cancel(nil)
+
+		close(p.results)
+		close(p.done)
+	}()
+
+	return p
+}
+
+// Submit queues v for tenant at the given priority (0 is the most urgent).
+// It blocks while the queue is full and returns the cancellation cause if
+// the pool is cancelled. It must not be called after Close.
+// It panics if priority is out of range.
+func (p *PriorityPool[In, Out]) Submit(tenant string, priority int, v In) error {
+	if priority < 0 || priority >= len(p.levels) {
+		panic("pool: priority out of range")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.cfg.MaxQueued > 0 && p.queued >= p.cfg.MaxQueued && p.ctx.Err() == nil {
+		p.cond.Wait()
+	}
+	if p.ctx.Err() != nil {
+		return context.Cause(p.ctx)
+	}
+
+	l := p.levels[priority]
+	if len(l.queues[tenant]) == 0 {
+		l.ring = append(l.ring, tenant)
+	}
+	l.queues[tenant] = append(l.queues[tenant], v)
+	p.queued++
+
+	// Broadcast rather than Signal: the waiter may be a blocked Submit.
+	p.cond.Broadcast()
+	return nil
+}
+
+// Results returns the channel the workers send their outputs on.
+// It is closed once every worker has exited.
+func (p *PriorityPool[In, Out]) Results() <-chan Out {
+	return p.results
+}
+
+// Close signals that no more tasks will be submitted. Workers drain the
+// queues and then exit. It is safe to call Close more than once.
+func (p *PriorityPool[In, Out]) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	p.cond.Broadcast()
+}
+
+// Abort cancels the pool with err as the cause.
+func (p *PriorityPool[In, Out]) Abort(err error) {
+	p.cancel(err)
+}
+
+// Wait blocks until every worker has exited and returns the first error.
+func (p *PriorityPool[In, Out]) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// worker takes tasks from the scheduler until it runs dry.
+func (p *PriorityPool[In, Out]) worker() {
+	defer p.wg.Done()
+
+	for {
+		v, ok := p.next()
+		if !ok {
+			return
+		}
+
+		out, err := call(p.fn, p.ctx, v)
+		if err != nil && !isolated(err) {
+			p.Abort(err)
+			return
+		}
+		p.record(err, false)
+		if err != nil {
+			continue // A panic: no result, but the worker carries on.
+		}
+
+		select {
+		case p.results <- out:
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// next blocks until there is a task to run and returns it, or returns
+// false once the pool is closed and drained, or cancelled.
+func (p *PriorityPool[In, Out]) next() (In, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.queued == 0 && !p.closed && p.ctx.Err() == nil {
+		p.cond.Wait()
+	}
+	if p.queued == 0 || p.ctx.Err() != nil {
+		var zero In
+		return zero, false
+	}
+
+	i := p.pick()
+	v := p.levels[i].pop(p.cfg.Weights)
+	p.queued--
+	p.cond.Broadcast() // A Submit may be waiting for room.
+	return v, true
+}
+
+// pick returns the level to serve next. p.mu must be held and at least
+// one task must be queued.
+func (p *PriorityPool[In, Out]) pick() int {
+	// The most urgent level that has waited too long goes first...
+	chosen := -1
+	for i, l := range p.levels {
+		if len(l.ring) > 0 && p.skipped[i] >= p.cfg.StarvationLimit {
+			chosen = i
+			break
+		}
+	}
+	// ...otherwise the most urgent non-empty level.
+	if chosen < 0 {
+		for i, l := range p.levels {
+			if len(l.ring) > 0 {
+				chosen = i
+				break
+			}
+		}
+	}
+
+	// Every less urgent level with work was passed over once more.
+	p.skipped[chosen] = 0
+	for i := chosen + 1; i < len(p.levels); i++ {
+		if len(p.levels[i].ring) > 0 {
+			p.skipped[i]++
+		}
+	}
+	return chosen
+}
+
+// pop takes the next task of the level in weighted round robin order.
+// The level must not be empty.
+func (l *level[In]) pop(weights map[string]int) In {
+	tenant := l.ring[l.cur]
+	if l.credit == 0 {
+		l.credit = max(weights[tenant], 1)
+	}
+
+	q := l.queues[tenant]
+	v := q[0]
+	var zero In
+	q[0] = zero // Don't keep the task alive through the backing array.
+	q = q[1:]
+	l.credit--
+
+	if len(q) == 0 {
+		// The tenant is done for now: drop it from the ring. The next
+		// tenant slides into position 'cur'.
+		delete(l.queues, tenant)
+		l.ring = append(l.ring[:l.cur], l.ring[l.cur+1:]...)
+		l.credit = 0
+	} else {
+		l.queues[tenant] = q
+		if l.credit == 0 {
+			l.cur++ // Its turn is over.
+		}
+	}
+	if l.cur >= len(l.ring) {
+		l.cur = 0
+	}
+	return v
+}
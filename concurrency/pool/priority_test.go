@@ -0,0 +1,124 @@
+package pool_test
+
+import (
+	"context"
+	"testing"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+func TestPriorityPoolIsolatesPanics(t *testing.T) {
+	p := pool.NewPriority(context.Background(), 2, func(_ context.Context, v int) (int, error) {
+		if v%10 == 3 {
+			panic("boom")
+		}
+		return v, nil
+	}, pool.PriorityConfig{})
+	go func() {
+		defer p.Close()
+		for i := 0; i < 100; i++ {
+			if err := p.Submit("tenant", i%3, i); err != nil {
+				return
+			}
+		}
+	}()
+
+	results := 0
+	for range p.Results() {
+		results++
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	if s := p.Summary(); results != 90 || s.Succeeded != 90 || s.Panicked != 10 {
+		t.Errorf("%d results, summary %+v; want 90 successes and 10 panics", results, s)
+	}
+}
+
+func TestPriorityPoolFairness(t *testing.T) {
+	type job struct {
+		tenant string
+		level  int
+	}
+	loads := []struct {
+		job
+		count int
+	}{
+		{job{"urgent", 0}, 500},
+		{job{"reports", 1}, 100},
+		{job{"bulk-a", 2}, 500},
+		{job{"bulk-b", 2}, 50},
+	}
+	cfg := pool.PriorityConfig{Levels: 3, StarvationLimit: 8}
+
+	// A single worker, held up by a first task until everything is queued,
+	// makes the service order deterministic.
+	started, release := make(chan struct{}), make(chan struct{})
+	p := pool.NewPriority(context.Background(), 1, func(_ context.Context, j job) (job, error) {
+		if j.tenant == "gate" {
+			close(started)
+			<-release
+		}
+		return j, nil
+	}, cfg)
+
+	p.Submit("gate", 0, job{"gate", 0})
+	<-started
+	pending := make(map[job]int)
+	for i := 0; i < 500; i++ {
+		for _, l := range loads {
+			if i < l.count {
+				p.Submit(l.tenant, l.level, l.job)
+				pending[l.job]++
+			}
+		}
+	}
+	p.Close()
+	close(release)
+
+	served := make(map[string]int)
+	passedOver := make([]int, cfg.Levels) // Tasks served while the level waited.
+	for j := range p.Results() {
+		if j.tenant == "gate" {
+			continue
+		}
+		pending[j]--
+		served[j.tenant]++
+
+		// A level with queued work is never passed over for long...
+		for level := range passedOver {
+			waiting := false
+			for k, n := range pending {
+				waiting = waiting || k.level == level && n > 0
+			}
+			switch {
+			case level == j.level || !waiting:
+				passedOver[level] = 0
+			default:
+				passedOver[level]++
+			}
+			if limit := cfg.StarvationLimit + cfg.Levels - 1; passedOver[level] > limit {
+				t.Fatalf("level %d passed over %d times in a row after %v, want at most %d",
+					level, passedOver[level], served, limit)
+			}
+		}
+
+		// ...and bulk-b gets as many turns as bulk-a while it has work.
+		if pending[job{"bulk-b", 2}] > 0 {
+			if d := served["bulk-a"] - served["bulk-b"]; d < 0 || d > 1 {
+				t.Fatalf("bulk-a served %d times, bulk-b %d; want them to take turns",
+					served["bulk-a"], served["bulk-b"])
+			}
+		}
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	for _, l := range loads {
+		if served[l.tenant] != l.count {
+			t.Errorf("%s served %d times, want %d", l.tenant, served[l.tenant], l.count)
+		}
+	}
+}
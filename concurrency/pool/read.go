@@ -0,0 +1,227 @@
+package pool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+)
+
+// ChunkSize is the size of the buffer Read hands to every task,
+// the same 1024 bytes the book's read() uses.
+const ChunkSize = 1024
+
+// ReadOption configures ReadContext.
+type ReadOption func(*readConfig)
+
+type readConfig struct {
+	split     bufio.SplitFunc // nil means raw ChunkSize reads.
+	maxRecord int             // 0 means bufio.MaxScanTokenSize.
+	recycle   bool
+	poolOpts  []Option
+}
+
+// WithFraming makes the producer cut the input with split (see Delimited,
+// Lines, LengthPrefixed and FixedSize) so that every task receives exactly
+// one record. Without it, tasks receive raw chunks of up to ChunkSize bytes.
+func WithFraming(split bufio.SplitFunc) ReadOption {
+	return func(c *readConfig) {
+		c.split = split
+	}
+}
+
+// WithMaxRecordSize sets the largest record the framing accepts.
+// Longer records make ReadContext fail with bufio.ErrTooLong.
+func WithMaxRecordSize(n int) ReadOption {
+	return func(c *readConfig) {
+		c.maxRecord = n
+	}
+}
+
+// WithBufferRecycling makes the producer borrow buffers from a sync.Pool
+// instead of allocating one per task. A buffer goes back to the pool once
+// its task has settled for good (it succeeded, or a Retry policy gave up on
+// it) and no run of the task is still executing, including a run abandoned
+// by TaskTimeout; until then, every retry sees the original bytes.
+//
+// This changes the ownership contract: the slice a task receives is only
+// valid until the task returns. A task that needs the bytes afterwards must
+// copy them (bytes.Clone). The slice is capped at its length, so appending
+// to it allocates rather than scribbling over the pooled buffer, and released
+// buffers are overwritten with 0xDB so that a retained reference shows up as
+// garbage instead of as another task's data.
+func WithBufferRecycling() ReadOption {
+	return func(c *readConfig) {
+		c.recycle = true
+	}
+}
+
+// WithPoolOptions passes opts on to the pool ReadContext creates,
+// e.g. TaskTimeout.
+func WithPoolOptions(opts ...Option) ReadOption {
+	return func(c *readConfig) {
+		c.poolOpts = append(c.poolOpts, opts...)
+	}
+}
+
+// Read is read() from concurrency/worker-pool-pattern rebuilt on top of Pool.
+//
+// It reads r in chunks of at most ChunkSize bytes, runs taskFunc over every
+// chunk using n workers and returns the sum of everything taskFunc returned.
+// If r fails with anything other than io.EOF, Read still shuts the pool down
+// and returns that error.
+//
+// A task that panics doesn't crash the process, but it doesn't go unnoticed
+// either: the other chunks are still summed, and Read returns that partial
+// sum along with an error that wraps every *PanicError.
+func Read(r io.Reader, taskFunc func([]byte) int, n int) (int64, error) {
+	count, summary, err := ReadContext(context.Background(), r, n, func(_ context.Context, b []byte) (int, error) {
+		return taskFunc(b), nil
+	})
+	if err == nil && len(summary.Errors) > 0 {
+		err = errors.Join(summary.Errors...)
+	}
+	return count, err
+}
+
+// ReadContext is the cancellable version of Read.
+//
+// The producer loop stops as soon as ctx is done, the reader fails or a task
+// returns an error; the first of those errors is returned. Whatever the
+// outcome, every worker has exited by the time ReadContext returns.
+//
+// The producer is another matter. A blocked r.Read call cannot be
+// interrupted, so when ReadContext returns early (ctx is done or a task
+// failed), the producer goroutine may still be blocked reading r: it
+// submits nothing more and exits as soon as that call returns, but until
+// then it leaks and r is still in use. Callers that need it gone must make
+// the read return, e.g. by closing r or setting a deadline on it.
+//
+// Tasks that panic or time out don't count towards the sum; they are
+// reported in the returned Summary instead.
+func ReadContext(ctx context.Context, r io.Reader, n int, taskFunc Func[[]byte, int], opts ...ReadOption) (int64, Summary, error) {
+	var cfg readConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var bufs *bufferPool
+	if cfg.recycle {
+		bufs = newBufferPool()
+	}
+
+	p := withSettled(ctx, n, func(ctx context.Context, c chunk) (int, error) {
+		// This run reads the buffer until it returns, even if TaskTimeout
+		// has long given up on it and a retry is already running.
+		if c.buf != nil {
+			bufs.hold(c.buf)
+			defer bufs.release(c.buf)
+		}
+		return taskFunc(ctx, c.data)
+	}, func(c chunk) {
+		// The task won't run again: drop the reference the producer took.
+		if c.buf != nil {
+			bufs.release(c.buf)
+		}
+	}, cfg.poolOpts...)
+
+	// --- Read Loop (The "Producer") ---
+	// It runs in its own goroutine so that the caller can consume results
+	// while the input is still being read.
+	go func() {
+		// Whatever happens, the workers must be told there is no more work.
+		// This is the close(ch) the original read() skips on its error path.
+		defer p.Close()
+
+		var err error
+		if cfg.split != nil {
+			err = produceRecords(p, r, cfg, bufs)
+		} else {
+			err = produceChunks(p, r, bufs)
+		}
+		if err != nil {
+			p.Abort(err) // A real error occurred.
+		}
+	}()
+
+	// --- Aggregation (The "Consumer") ---
+	// This replaces the atomic.AddInt64 of the original: only this
+	// goroutine touches 'count', so no synchronisation is needed.
+	var count int64
+	for v := range p.Results() {
+		count += int64(v)
+	}
+	// With a Retry policy, tasks that failed for good are counted in the
+	// Summary; the channel still has to be drained.
+	for range p.DeadLetters() {
+	}
+
+	if err := p.Wait(); err != nil {
+		return 0, p.Summary(), err
+	}
+	return count, p.Summary(), nil
+}
+
+// produceChunks is the original producer: it submits whatever every
+// r.Read call returns, up to ChunkSize bytes at a time.
+// If bufs is not nil, buffers are borrowed from it instead of allocated.
+func produceChunks(p *Pool[chunk, int], r io.Reader, bufs *bufferPool) error {
+	for {
+		var c chunk
+		if bufs != nil {
+			c.buf = bufs.get()
+			c.data = c.buf.b[:ChunkSize]
+		} else {
+			c.data = make([]byte, ChunkSize)
+		}
+
+		nRead, err := r.Read(c.data)
+		// An io.Reader may return data *and* an error in the same call,
+		// so the data is handed over before the error is looked at.
+		if nRead > 0 {
+			// Cap the slice so that an append inside the task can't
+			// write past the data into the rest of a pooled buffer.
+			c.data = c.data[:nRead:nRead]
+			if p.Submit(c) != nil {
+				return nil // The pool was cancelled; Wait knows why.
+			}
+		} else if c.buf != nil {
+			bufs.release(c.buf) // Nothing was read, so nobody else owns it.
+		}
+		if err == io.EOF {
+			return nil // End of file, stop sending tasks.
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// produceRecords submits one task per record found by cfg.split.
+// If bufs is not nil, records are copied into borrowed buffers.
+func produceRecords(p *Pool[chunk, int], r io.Reader, cfg readConfig, bufs *bufferPool) error {
+	sc := bufio.NewScanner(r)
+	sc.Split(cfg.split)
+	if cfg.maxRecord > 0 {
+		sc.Buffer(nil, cfg.maxRecord)
+	}
+
+	for sc.Scan() {
+		// The scanner reuses its buffer on the next Scan, while the worker
+		// may still be busy with this record, so the task gets its own copy.
+		var c chunk
+		if bufs != nil {
+			c.buf = bufs.get()
+			c.buf.b = append(c.buf.b[:0], sc.Bytes()...)
+			c.data = c.buf.b[:len(c.buf.b):len(c.buf.b)]
+		} else {
+			c.data = bytes.Clone(sc.Bytes())
+		}
+
+		if p.Submit(c) != nil {
+			return nil // The pool was cancelled; Wait knows why.
+		}
+	}
+	return sc.Err()
+}
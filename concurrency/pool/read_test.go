@@ -0,0 +1,202 @@
+package pool_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+// intact reports whether b still holds the 'a's the tests feed in,
+// i.e. whether its buffer was not recycled (and poisoned) under it.
+func intact(b []byte) bool {
+	return len(b) > 0 && bytes.Count(b, []byte{'a'}) == len(b)
+}
+
+func TestReadContextRecyclingSurvivesRetries(t *testing.T) {
+	const chunks = 8
+	input := strings.Repeat("a", chunks*pool.ChunkSize)
+
+	var calls, corrupt atomic.Int64
+	task := func(_ context.Context, b []byte) (int, error) {
+		if !intact(b) {
+			corrupt.Add(1)
+		}
+		// Every other run fails, so every chunk is retried at least once
+		// after its first run has returned.
+		if calls.Add(1)%2 == 1 {
+			return 0, errors.New("transient")
+		}
+		return len(b), nil
+	}
+
+	count, summary, err := pool.ReadContext(context.Background(), strings.NewReader(input), 2, task,
+		pool.WithBufferRecycling(),
+		pool.WithPoolOptions(pool.Retry(pool.RetryPolicy{MaxAttempts: 10})))
+	if err != nil {
+		t.Fatalf("ReadContext: %v", err)
+	}
+	if count != int64(len(input)) {
+		t.Errorf("count = %d, want %d (summary %+v)", count, len(input), summary)
+	}
+	if summary.Retried == 0 {
+		t.Errorf("no task was retried: %+v", summary)
+	}
+	if n := corrupt.Load(); n > 0 {
+		t.Errorf("%d runs saw a recycled buffer", n)
+	}
+}
+
+func TestReadContextRecyclingSurvivesAbandonedRuns(t *testing.T) {
+	const chunks = 4
+	input := strings.Repeat("a", chunks*pool.ChunkSize)
+
+	var (
+		abandoned sync.WaitGroup
+		started   sync.Map // The buffers whose first run already started.
+		corrupt   atomic.Int64
+	)
+	abandoned.Add(chunks)
+	task := func(_ context.Context, b []byte) (int, error) {
+		if !intact(b) {
+			corrupt.Add(1)
+			return 0, nil
+		}
+		if _, retry := started.LoadOrStore(&b[0], true); retry {
+			return len(b), nil
+		}
+
+		// The first run ignores its context, outlives TaskTimeout
+		// and reads the buffer again after its retry has finished.
+		defer abandoned.Done()
+		time.Sleep(50 * time.Millisecond)
+		if !intact(b) {
+			corrupt.Add(1)
+		}
+		return len(b), nil
+	}
+
+	count, summary, err := pool.ReadContext(context.Background(), strings.NewReader(input), chunks, task,
+		pool.WithBufferRecycling(),
+		pool.WithPoolOptions(
+			pool.TaskTimeout(10*time.Millisecond),
+			pool.Retry(pool.RetryPolicy{MaxAttempts: 2})))
+	if err != nil {
+		t.Fatalf("ReadContext: %v", err)
+	}
+	if count != int64(len(input)) || summary.TimedOut != 0 || summary.Retried != chunks {
+		t.Errorf("count = %d, summary %+v; want %d with %d retries", count, summary, len(input), chunks)
+	}
+
+	abandoned.Wait()
+	if n := corrupt.Load(); n > 0 {
+		t.Errorf("%d runs saw a recycled buffer", n)
+	}
+}
+
+func TestReadReportsPanics(t *testing.T) {
+	input := strings.Repeat("a", 4*pool.ChunkSize)
+
+	var calls atomic.Int64
+	count, err := pool.Read(strings.NewReader(input), func(b []byte) int {
+		if calls.Add(1) == 2 {
+			panic("boom")
+		}
+		return len(b)
+	}, 2)
+
+	var pe *pool.PanicError
+	if !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Fatalf("err = %v, want a *PanicError for %q", err, "boom")
+	}
+	if want := int64(3 * pool.ChunkSize); count != want {
+		t.Errorf("count = %d, want the %d bytes of the chunks that didn't panic", count, want)
+	}
+}
+
+// allocCases are the producer configurations whose allocations
+// BenchmarkReadContext compares.
+var allocCases = []struct {
+	name string
+	opts []pool.ReadOption
+}{
+	{"chunks", nil},
+	{"chunks+recycling", []pool.ReadOption{pool.WithBufferRecycling()}},
+	{"delimited", []pool.ReadOption{pool.WithFraming(pool.Delimited('|'))}},
+	{"delimited+recycling", []pool.ReadOption{pool.WithFraming(pool.Delimited('|')), pool.WithBufferRecycling()}},
+}
+
+// allocInput is big enough for the per-task allocations of the producer
+// to dominate the fixed cost of starting a pool.
+var allocInput = strings.Repeat("some-data-packet|", 10_000)
+
+// countOne is a task that costs nothing.
+func countOne(context.Context, []byte) (int, error) {
+	return 1, nil
+}
+
+func TestBufferRecyclingSavesAllocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("measures allocations over many runs")
+	}
+
+	perTask := make(map[string]float64)
+	for _, c := range allocCases {
+		tasks, _, err := pool.ReadContext(context.Background(), strings.NewReader(allocInput), 1, countOne, c.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		allocs := testing.AllocsPerRun(5, func() {
+			pool.ReadContext(context.Background(), strings.NewReader(allocInput), 1, countOne, c.opts...)
+		})
+		perTask[c.name] = allocs / float64(tasks)
+	}
+
+	// Without recycling, every task costs one buffer; with it, most buffers
+	// come back from the sync.Pool. How many depends on the GC, and the race
+	// detector makes the pool drop some on purpose, so only the order is
+	// checked.
+	for _, framing := range []string{"chunks", "delimited"} {
+		plain, recycled := perTask[framing], perTask[framing+"+recycling"]
+		if plain < 1 || recycled >= plain {
+			t.Errorf("%s: %.2f allocs/task, %.2f with recycling; want at least 1, and fewer with recycling",
+				framing, plain, recycled)
+		}
+	}
+}
+
+// BenchmarkReadContext reports the allocations of the producer per task,
+// the -benchmem columns divided by the number of tasks of a run:
+//
+//	go test -bench ReadContext -benchmem ./concurrency/pool
+func BenchmarkReadContext(b *testing.B) {
+	for _, c := range allocCases {
+		b.Run(c.name, func(b *testing.B) {
+			tasks, _, err := pool.ReadContext(context.Background(), strings.NewReader(allocInput), 1, countOne, c.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			for i := 0; i < b.N; i++ {
+				pool.ReadContext(context.Background(), strings.NewReader(allocInput), 1, countOne, c.opts...)
+			}
+			b.StopTimer()
+			runtime.ReadMemStats(&after)
+
+			runs := float64(b.N) * float64(tasks)
+			b.ReportMetric(float64(after.Mallocs-before.Mallocs)/runs, "allocs/task")
+			b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/runs, "B/task")
+		})
+	}
+}
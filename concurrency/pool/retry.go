@@ -0,0 +1,208 @@
+package pool
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"advanced-concepts/concurrency/internal/conc"
+)
+
+// Backoff computes the delay before a retry: Base, then Base*Multiplier,
+// Base*Multiplier², ... capped at Max, with a random Jitter share removed
+// so that tasks that failed together don't all come back at the same time.
+type Backoff = conc.Backoff
+
+// Clock schedules the re-queueing of failed tasks. It is an interface
+// so that retries can be driven by a ManualClock instead of real time.
+type Clock interface {
+	// AfterFunc calls f in its own goroutine once d has elapsed.
+	AfterFunc(d time.Duration, f func())
+}
+
+// realClock is the Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) AfterFunc(d time.Duration, f func()) {
+	time.AfterFunc(d, f)
+}
+
+// ManualClock is a Clock whose time only moves when Advance is called,
+// which makes retry schedules deterministic. The zero value is ready to use.
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Duration
+	timers []manualTimer
+}
+
+type manualTimer struct {
+	at time.Duration
+	f  func()
+}
+
+// AfterFunc implements Clock.
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timers = append(c.timers, manualTimer{at: c.now + d, f: f})
+}
+
+// Advance moves the clock forward by d and starts, in their own
+// goroutines and in deadline order, the functions that are now due.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now += d
+
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at < c.timers[j].at })
+	var due []manualTimer
+	for len(c.timers) > 0 && c.timers[0].at <= c.now {
+		due = append(due, c.timers[0])
+		c.timers = c.timers[1:]
+	}
+	c.mu.Unlock()
+
+	for _, t := range due {
+		go t.f()
+	}
+}
+
+// Pending returns how many scheduled functions are not due yet.
+func (c *ManualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// RetryPolicy describes how failed tasks are retried, see Retry.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of runs per task, the first one
+	// included. 1 or less means failed tasks go straight to the dead letters.
+	MaxAttempts int
+
+	Backoff Backoff
+
+	// Retryable decides whether an error is worth another attempt.
+	// nil retries everything except panics, which are bugs, not hiccups.
+	Retryable func(error) bool
+
+	// Clock schedules the retries; nil means real time.
+	Clock Clock
+
+	// Rand returns the random numbers in [0, 1) used for the jitter;
+	// nil means math/rand/v2.
+	Rand func() float64
+}
+
+// DeadLetter is a task that failed for good.
+type DeadLetter[In any] struct {
+	Value    In
+	Attempts int   // How many times it ran.
+	Err      error // The error of the last attempt.
+}
+
+// Retry makes the pool retry failed tasks instead of aborting on the first
+// error. A failed task is handed to the Clock and re-queued once its backoff
+// has elapsed, so the worker that ran it moves on right away. A task that
+// fails for good (out of attempts, or not Retryable) is sent to DeadLetters.
+func Retry(policy RetryPolicy) Option {
+	if policy.Retryable == nil {
+		policy.Retryable = func(err error) bool {
+			var pe *PanicError
+			return !errors.As(err, &pe)
+		}
+	}
+	if policy.Clock == nil {
+		policy.Clock = realClock{}
+	}
+	if policy.Rand == nil {
+		policy.Rand = rand.Float64
+	}
+
+	return func(c *config) {
+		c.retry = &policy
+	}
+}
+
+// DeadLetters returns the channel of tasks that failed for good.
+// It is only written to by pools with a Retry policy, and it is closed
+// once all of them have been delivered after the workers exited.
+//
+// Dead letters are queued without bound, so it is fine to drain Results
+// first and DeadLetters second, but a Retry pool's DeadLetters must be
+// drained eventually, or the goroutine delivering them never exits.
+func (p *Pool[In, Out]) DeadLetters() <-chan DeadLetter[In] {
+	return p.deadLetters
+}
+
+// requeue schedules t for another attempt after its backoff.
+func (p *Pool[In, Out]) requeue(t task[In]) {
+	p.statsMu.Lock()
+	p.summary.Retried++
+	p.statsMu.Unlock()
+
+	delay := p.retry.Backoff.Delay(t.attempt, p.retry.Rand())
+	t.attempt++
+
+	p.retry.Clock.AfterFunc(delay, func() {
+		if !t.queued.IsZero() {
+			t.queued = time.Now()
+		}
+		// The tasks channel can't be closed under us: Close waits
+		// until no task is outstanding, and this one still is.
+		select {
+		case p.tasks <- t:
+		case <-p.ctx.Done():
+		}
+	})
+}
+
+// deadLetter records t as failed for good and queues it for DeadLetters.
+func (p *Pool[In, Out]) deadLetter(t task[In], err error) {
+	p.dlMu.Lock()
+	p.dlQueue = append(p.dlQueue, DeadLetter[In]{Value: t.v, Attempts: t.attempt, Err: err})
+	p.dlMu.Unlock()
+	p.dlCond.Signal()
+}
+
+// forwardDeadLetters delivers queued dead letters on the DeadLetters channel
+// and closes it once the workers are gone and the queue is empty.
+func (p *Pool[In, Out]) forwardDeadLetters() {
+	defer close(p.deadLetters)
+
+	for {
+		p.dlMu.Lock()
+		for len(p.dlQueue) == 0 && !p.dlDone {
+			p.dlCond.Wait()
+		}
+		if len(p.dlQueue) == 0 {
+			p.dlMu.Unlock()
+			return
+		}
+		dl := p.dlQueue[0]
+		p.dlQueue = p.dlQueue[1:]
+		p.dlMu.Unlock()
+
+		p.deadLetters <- dl
+	}
+}
+
+// track counts a newly submitted task as outstanding in a Retry pool.
+func (p *Pool[In, Out]) track() {
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	p.outstanding++
+}
+
+// finish marks a task as settled (done or dead) in a Retry pool, and closes
+// the tasks channel if Close was called and nothing can come back any more.
+func (p *Pool[In, Out]) finish() {
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+
+	p.outstanding--
+	if p.closing && p.outstanding == 0 {
+		close(p.tasks)
+	}
+}
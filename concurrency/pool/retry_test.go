@@ -0,0 +1,123 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+// recordingClock is a ManualClock that remembers the delays it was given.
+type recordingClock struct {
+	pool.ManualClock
+
+	mu     sync.Mutex
+	delays []time.Duration
+}
+
+func (c *recordingClock) AfterFunc(d time.Duration, f func()) {
+	c.mu.Lock()
+	c.delays = append(c.delays, d)
+	c.mu.Unlock()
+	c.ManualClock.AfterFunc(d, f)
+}
+
+func TestRetrySchedule(t *testing.T) {
+	const step = 10 * time.Millisecond
+	errUnavailable := errors.New("service unavailable")
+	errInvalid := errors.New("invalid request")
+
+	clock := &recordingClock{}
+	var virtual atomic.Int64 // The time.Duration the clock was advanced by.
+
+	var mu sync.Mutex
+	attempts := make(map[string][]time.Duration) // When each run of a value started.
+	task := func(_ context.Context, v string) (string, error) {
+		mu.Lock()
+		attempts[v] = append(attempts[v], time.Duration(virtual.Load()))
+		n := len(attempts[v])
+		mu.Unlock()
+
+		switch {
+		case v == "broken":
+			return "", errInvalid
+		case v == "down", v == "flaky" && n <= 2:
+			return "", errUnavailable
+		}
+		return fmt.Sprintf("%s (attempt %d)", v, n), nil
+	}
+
+	p := pool.WithContext(context.Background(), 4, task, pool.Retry(pool.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     pool.Backoff{Base: 100 * time.Millisecond, Max: time.Second, Jitter: 0.2},
+		Retryable:   func(err error) bool { return errors.Is(err, errUnavailable) },
+		Clock:       clock,
+		Rand:        func() float64 { return 0.5 },
+	}))
+	submitAll(p, []string{"a", "flaky", "b", "down", "broken"})
+
+	// Move virtual time in steps for as long as retries are waiting.
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(100 * time.Microsecond):
+				if clock.Pending() > 0 {
+					virtual.Add(int64(step))
+					clock.Advance(step)
+				}
+			}
+		}
+	}()
+
+	results := drain(p.Results())
+	deadLetters := drain(p.DeadLetters())
+	err := p.Wait()
+	close(stop)
+	<-stopped
+	if err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+
+	slices.Sort(results)
+	if want := []string{"a (attempt 1)", "b (attempt 1)", "flaky (attempt 3)"}; !slices.Equal(results, want) {
+		t.Errorf("results = %q, want %q", results, want)
+	}
+
+	slices.SortFunc(deadLetters, func(a, b pool.DeadLetter[string]) int { return len(a.Value) - len(b.Value) })
+	if len(deadLetters) != 2 ||
+		deadLetters[0].Value != "down" || deadLetters[0].Attempts != 3 || !errors.Is(deadLetters[0].Err, errUnavailable) ||
+		deadLetters[1].Value != "broken" || deadLetters[1].Attempts != 1 || !errors.Is(deadLetters[1].Err, errInvalid) {
+		t.Errorf("dead letters = %+v, want down after 3 attempts and broken after 1", deadLetters)
+	}
+
+	s := p.Summary()
+	if s.Succeeded != 3 || s.Retried != 4 || s.DeadLettered != 2 {
+		t.Errorf("summary %+v, want 3 succeeded, 4 retried, 2 dead-lettered", s)
+	}
+
+	// Half the jitter share comes off: 100ms and 200ms, less 10%.
+	delays := slices.Clone(clock.delays)
+	slices.Sort(delays)
+	if want := []time.Duration{90 * time.Millisecond, 90 * time.Millisecond, 180 * time.Millisecond, 180 * time.Millisecond}; !slices.Equal(delays, want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+
+	// No retry ran before its backoff had elapsed in virtual time.
+	for _, v := range []string{"flaky", "down"} {
+		at := attempts[v]
+		if len(at) != 3 || at[1]-at[0] < 90*time.Millisecond || at[2]-at[1] < 180*time.Millisecond {
+			t.Errorf("%s ran at %v, want 3 runs at least 90ms, then 180ms apart", v, at)
+		}
+	}
+}
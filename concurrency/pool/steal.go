@@ -0,0 +1,277 @@
+package pool
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+)
+
+// Executor is what Pool and StealingPool have in common: the Submit,
+// Results, Close, Wait life cycle of the book's read().
+type Executor[In, Out any] interface {
+	Submit(v In) error
+	Results() <-chan Out
+	Close()
+	Wait() error
+}
+
+var (
+	_ Executor[int, int] = (*Pool[int, int])(nil)
+	_ Executor[int, int] = (*StealingPool[int, int])(nil)
+)
+
+// stealQueuePerWorker bounds the number of queued tasks per worker.
+const stealQueuePerWorker = 32
+
+// StealingPool is an alternative to Pool for many tiny tasks.
+//
+// Pool funnels every task through one shared channel, and with enough
+// workers that channel's lock becomes the bottleneck. Here each worker owns
+// a deque instead: Submit spreads tasks over the deques round robin, a
+// worker takes its newest task from the back of its own deque, and a worker
+// whose deque is empty steals the oldest task from the front of another's.
+// Locks are per deque, so workers only meet when one of them steals.
+//
+// Apart from the scheduling, it behaves like a WithContext Pool: the first
+// task error cancels the pool, while panics are isolated and counted in
+// the Summary.
+type StealingPool[In, Out any] struct {
+	fn Func[In, Out]
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	deques []deque[In]
+	next   atomic.Uint64 // Round-robin cursor of Submit.
+	queued atomic.Int64  // Tasks in all deques.
+	idle   atomic.Int64  // Workers parked, or about to park, on 'wake'.
+	slots  chan struct{} // Bounds the queued tasks; Submit blocks when full.
+	wake   chan struct{} // Wakes parked workers when a task arrives.
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	results chan Out
+	wg      sync.WaitGroup
+	done    chan struct{}
+	err     error
+
+	stats
+}
+
+// deque is one worker's double-ended queue.
+type deque[In any] struct {
+	mu    sync.Mutex
+	tasks []In
+}
+
+func (d *deque[In]) pushBack(v In) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tasks = append(d.tasks, v)
+}
+
+// popBack takes the newest task: the owner's end, where the data the
+// worker just touched is most likely still in cache.
+func (d *deque[In]) popBack() (In, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var zero In
+	if len(d.tasks) == 0 {
+		return zero, false
+	}
+	v := d.tasks[len(d.tasks)-1]
+	d.tasks[len(d.tasks)-1] = zero
+	d.tasks = d.tasks[:len(d.tasks)-1]
+	return v, true
+}
+
+// popFront takes the oldest task: the thieves' end.
+func (d *deque[In]) popFront() (In, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var zero In
+	if len(d.tasks) == 0 {
+		return zero, false
+	}
+	v := d.tasks[0]
+	d.tasks[0] = zero
+	d.tasks = d.tasks[1:]
+	return v, true
+}
+
+// NewStealing creates a work-stealing pool of n workers bound to ctx.
+// It panics if n is less than 1.
+func NewStealing[In, Out any](ctx context.Context, n int, fn Func[In, Out]) *StealingPool[In, Out] {
+	if n < 1 {
+		panic("pool: n must be at least 1")
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	p := &StealingPool[In, Out]{
+		fn:      fn,
+		ctx:     ctx,
+		cancel:  cancel,
+		deques:  make([]deque[In], n),
+		slots:   make(chan struct{}, n*stealQueuePerWorker),
+		wake:    make(chan struct{}, n),
+		closed:  make(chan struct{}),
+		results: make(chan Out, n),
+		done:    make(chan struct{}),
+	}
+
+	p.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go p.worker(i)
+	}
+
+	go func() {
+		p.wg.Wait()
+
+		if p.ctx.Err() != nil {
+			p.err = context.Cause(p.ctx)
+		}
+		p.cancel(nil)
+
+		close(p.results)
+		close(p.done)
+	}()
+
+	return p
+}
+
+// Submit queues v on the next worker's deque. It blocks while the pool
+// is full and returns the cancellation cause if the pool is cancelled.
+// It must not be called after Close.
+func (p *StealingPool[In, Out]) Submit(v In) error {
+	if p.ctx.Err() != nil {
+		return context.Cause(p.ctx)
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.ctx.Done():
+		return context.Cause(p.ctx)
+	}
+
+	i := (p.next.Add(1) - 1) % uint64(len(p.deques))
+	p.deques[i].pushBack(v)
+	p.queued.Add(1)
+
+	// Only pay for the wake-up when somebody is parked.
+	if p.idle.Load() > 0 {
+		select {
+		case p.wake <- struct{}{}:
+		default: // Enough wake-ups are pending already.
+		}
+	}
+	return nil
+}
+
+// Results returns the channel the workers send their outputs on.
+// It is closed once every worker has exited.
+func (p *StealingPool[In, Out]) Results() <-chan Out {
+	return p.results
+}
+
+// Close signals that no more tasks will be submitted. Workers drain the
+// deques and then exit. It is safe to call Close more than once.
+func (p *StealingPool[In, Out]) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+	})
+}
+
+// Abort cancels the pool with err as the cause.
+func (p *StealingPool[In, Out]) Abort(err error) {
+	p.cancel(err)
+}
+
+// Wait blocks until every worker has exited and returns the first error.
+func (p *StealingPool[In, Out]) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// take returns a task from worker i's own deque, or else one stolen
+// from another worker, starting at a random victim.
+func (p *StealingPool[In, Out]) take(i int) (In, bool) {
+	if v, ok := p.deques[i].popBack(); ok {
+		return v, true
+	}
+
+	n := len(p.deques)
+	start := rand.IntN(n)
+	for k := 0; k < n; k++ {
+		victim := (start + k) % n
+		if victim == i {
+			continue
+		}
+		if v, ok := p.deques[victim].popFront(); ok {
+			return v, true
+		}
+	}
+
+	var zero In
+	return zero, false
+}
+
+// worker runs tasks until the pool is closed and drained, or cancelled.
+func (p *StealingPool[In, Out]) worker(i int) {
+	defer p.wg.Done()
+
+	for p.ctx.Err() == nil {
+		v, ok := p.take(i)
+		if !ok {
+			if !p.park() {
+				return
+			}
+			continue
+		}
+		p.queued.Add(-1)
+		<-p.slots
+
+		out, err := call(p.fn, p.ctx, v)
+		if err != nil && !isolated(err) {
+			p.Abort(err)
+			return
+		}
+		p.record(err, false)
+		if err != nil {
+			continue // A panic: no result, but the worker carries on.
+		}
+
+		select {
+		case p.results <- out:
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// park waits for work after a worker found every deque empty. It reports
+// false once there will never be work again: closed and drained, or cancelled.
+func (p *StealingPool[In, Out]) park() bool {
+	// Announce ourselves *before* the last look at 'queued': Submit
+	// increments 'queued' before it looks at 'idle', so either it sees
+	// us and wakes us up, or we see its task here.
+	p.idle.Add(1)
+	defer p.idle.Add(-1)
+
+	if p.queued.Load() > 0 {
+		return true // A task is on its way into (or out of) a deque.
+	}
+
+	select {
+	case <-p.wake:
+		return true
+	case <-p.closed:
+		// Keep going while others' tasks may still need stealing.
+		return p.queued.Load() > 0
+	case <-p.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+func TestStealingPoolIsolatesPanics(t *testing.T) {
+	p := pool.NewStealing(context.Background(), 4, func(_ context.Context, v int) (int, error) {
+		if v%10 == 3 {
+			panic("boom")
+		}
+		return v, nil
+	})
+	go func() {
+		defer p.Close()
+		for i := 0; i < 100; i++ {
+			if err := p.Submit(i); err != nil {
+				return
+			}
+		}
+	}()
+
+	results := 0
+	for range p.Results() {
+		results++
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	s := p.Summary()
+	if results != 90 || s.Succeeded != 90 || s.Panicked != 10 {
+		t.Errorf("%d results, summary %+v; want 90 successes and 10 panics", results, s)
+	}
+	var pe *pool.PanicError
+	if len(s.Errors) == 0 || !errors.As(s.Errors[0], &pe) {
+		t.Errorf("Errors = %v, want *PanicErrors", s.Errors)
+	}
+}
+
+// executors builds the two pools behind their common interface.
+var executors = []struct {
+	name string
+	new  func(n int, fn pool.Func[int, int]) pool.Executor[int, int]
+}{
+	{"Pool", func(n int, fn pool.Func[int, int]) pool.Executor[int, int] {
+		return pool.WithContext(context.Background(), n, fn)
+	}},
+	{"StealingPool", func(n int, fn pool.Func[int, int]) pool.Executor[int, int] {
+		return pool.NewStealing(context.Background(), n, fn)
+	}},
+}
+
+// spin is a CPU-bound task of adjustable granularity: it loops
+// 'iterations' times.
+func spin(iterations int) pool.Func[int, int] {
+	return func(_ context.Context, v int) (int, error) {
+		sum := 0
+		for i := 0; i < iterations; i++ {
+			sum += (i * v) % 255
+		}
+		return sum & 1, nil
+	}
+}
+
+// sleep is an I/O-bound task: it waits for d.
+func sleep(d time.Duration) pool.Func[int, int] {
+	return func(context.Context, int) (int, error) {
+		time.Sleep(d)
+		return 1, nil
+	}
+}
+
+// runExecutor pushes 'tasks' tasks through e and counts the results,
+// like pool.Read does with an io.Reader.
+func runExecutor(e pool.Executor[int, int], tasks int) (int, error) {
+	submitAll(e, upTo(tasks))
+	return len(drain(e.Results())), e.Wait()
+}
+
+func TestExecutors(t *testing.T) {
+	for _, ex := range executors {
+		for _, workers := range []int{1, 3, 16} {
+			t.Run(fmt.Sprintf("%s/%d-workers", ex.name, workers), func(t *testing.T) {
+				got, err := runExecutor(ex.new(workers, spin(100)), 1000)
+				if err != nil || got != 1000 {
+					t.Errorf("%d results, %v; want 1000, nil", got, err)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkExecutor compares Pool and StealingPool over task granularities:
+// the finer the tasks, the more Pool's shared channel costs.
+//
+//	go test -bench Executor -benchmem ./concurrency/pool
+func BenchmarkExecutor(b *testing.B) {
+	cpus := runtime.GOMAXPROCS(0)
+	workloads := []struct {
+		name  string
+		fn    pool.Func[int, int]
+		size  int // Pool size.
+		tasks int // Tasks per run.
+	}{
+		{"CPU-tiny", spin(100), cpus, 20_000},
+		{"CPU-small", spin(10_000), cpus, 2_000},
+		{"CPU-large", spin(1_000_000), cpus, 20},
+		{"IO-1ms", sleep(time.Millisecond), 50, 500},
+	}
+
+	for _, wl := range workloads {
+		for _, ex := range executors {
+			b.Run(wl.name+"/"+ex.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := runExecutor(ex.new(wl.size, wl.fn), wl.tasks); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*wl.tasks), "ns/task")
+			})
+		}
+	}
+}
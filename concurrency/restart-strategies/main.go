@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"advanced-concepts/concurrency/supervisor"
+	"advanced-concepts/concurrency/tracing"
+)
+
+// --- Watcher-Style Workers ---
+
+// watcher returns the watch() loop of the stop-go-routine example as a
+// supervisor.Worker: it ticks until quit is closed, then cleans up.
+// If failAfter is set, its first 'failures' runs fail after that long,
+// by panicking or by returning an error.
+func watcher(failAfter time.Duration, failures int32, panics bool) supervisor.Worker {
+	var runs atomic.Int32
+
+	return func(quit <-chan struct{}) error {
+		run := runs.Add(1)
+
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		var fail <-chan time.Time // nil: never fails.
+		if failAfter > 0 && run <= failures {
+			fail = time.After(failAfter)
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+				// ...doing work (watching)...
+
+			case <-fail:
+				if panics {
+					panic(fmt.Sprintf("run %d: nil map write", run))
+				}
+				return fmt.Errorf("run %d: connection reset", run)
+
+			case <-quit:
+				time.Sleep(5 * time.Millisecond) // Cleaning up resources.
+				return nil
+			}
+		}
+	}
+}
+
+// children are three workers started in dependency order: the cache
+// needs the database, the API needs both. The cache fails twice.
+func children() []supervisor.Spec {
+	return []supervisor.Spec{
+		{Name: "db", Run: watcher(0, 0, false)},
+		{Name: "cache", Run: watcher(40*time.Millisecond, 2, true)},
+		{Name: "api", Run: watcher(0, 0, false)},
+	}
+}
+
+// printStatus prints the Status table of s.
+func printStatus(s *supervisor.Supervisor) {
+	fmt.Printf("   %-8s %-10s %8s  %s\n", "child", "state", "restarts", "last error")
+	for _, c := range s.Status() {
+		last := "-"
+		if c.LastError != nil {
+			last = c.LastError.Error()
+		}
+		fmt.Printf("   %-8s %-10s %8d  %s\n", c.Name, c.State, c.Restarts, last)
+	}
+}
+
+// --- Main Function to Run the Strategies ---
+
+func main() {
+	format := flag.String("trace", "off", "supervisor events: text, json or off")
+	flag.Parse()
+
+	t, err := tracing.ForFormat(*format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	for _, strategy := range []struct {
+		name string
+		s    supervisor.Strategy
+	}{
+		{"OneForOne: only the cache is restarted", supervisor.OneForOne},
+		{"OneForAll: every child is restarted with the cache", supervisor.OneForAll},
+		{"RestForOne: the cache and the API after it are restarted", supervisor.RestForOne},
+	} {
+		fmt.Println(strategy.name)
+		s := supervisor.New(supervisor.Config{Strategy: strategy.s, Trace: t}, children()...)
+		time.Sleep(300 * time.Millisecond)
+		printStatus(s)
+		fmt.Printf("   Stop: %v\n\n", s.Stop())
+	}
+
+	fmt.Println("Restart intensity: a child failing on every start exhausts 3 restarts per second")
+	start := time.Now()
+	s := supervisor.New(supervisor.Config{Strategy: supervisor.OneForOne, MaxRestarts: 3, Period: time.Second, Trace: t},
+		supervisor.Spec{Name: "db", Run: watcher(0, 0, false)},
+		supervisor.Spec{Name: "broken", Run: watcher(time.Millisecond, 1<<30, false)},
+	)
+	err = s.Wait()
+	fmt.Printf("   gave up after %v: %v (ErrTooManyRestarts: %v)\n",
+		time.Since(start).Round(10*time.Millisecond), err, errors.Is(err, supervisor.ErrTooManyRestarts))
+	printStatus(s)
+
+	fmt.Println("\nTransient and Temporary children that end are left alone")
+	s = supervisor.New(supervisor.Config{Trace: t},
+		supervisor.Spec{Name: "migrate", Restart: supervisor.Transient, Run: func(<-chan struct{}) error {
+			return nil // One-shot job: done.
+		}},
+		supervisor.Spec{Name: "probe", Restart: supervisor.Temporary, Run: watcher(20*time.Millisecond, 1, false)},
+		supervisor.Spec{Name: "api", Run: watcher(0, 0, false)},
+	)
+	time.Sleep(100 * time.Millisecond)
+	printStatus(s)
+	fmt.Printf("   Stop: %v\n", s.Stop())
+}
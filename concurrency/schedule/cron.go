@@ -0,0 +1,206 @@
+package schedule
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+	// Next returns the first run time after 'after', or the zero Time
+	// if there is none.
+	Next(after time.Time) time.Time
+}
+
+// every is the Schedule of Every.
+type every time.Duration
+
+func (e every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// Every runs a job at a fixed interval, like the watcher's ticker.
+// It panics if d is not positive.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("schedule: interval must be positive")
+	}
+	return every(d)
+}
+
+// jittered is the Schedule of WithJitter.
+type jittered struct {
+	s   Schedule
+	max time.Duration
+}
+
+func (j jittered) Next(after time.Time) time.Time {
+	next := j.s.Next(after)
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(rand.N(j.max))
+}
+
+// WithJitter delays every run of s by a random duration in [0, max), so
+// that jobs scheduled alike (say, in every process of a fleet) spread out
+// instead of all hitting their backend at the same instant.
+func WithJitter(s Schedule, max time.Duration) Schedule {
+	if max <= 0 {
+		return s
+	}
+	return jittered{s: s, max: max}
+}
+
+// cron is a parsed cron expression: one bit per allowed value.
+type cron struct {
+	expr                     string
+	minute, hour, dom, month uint64
+	dow                      uint64
+	domStar, dowStar         bool // The field starts with '*': see dayMatches.
+}
+
+// The bounds of the five fields.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday.
+}
+
+var cronMacros = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// Cron parses a standard five-field cron expression, "minute hour
+// day-of-month month day-of-week", where each field is '*', a value, a
+// range 'a-b', a step '*/n' or 'a-b/n', or a comma-separated list of those.
+// The macros @yearly, @monthly, @weekly, @daily and @hourly are accepted.
+// Times are computed in the location of the time passed to Next.
+func Cron(expr string) (Schedule, error) {
+	spec := expr
+	if m, ok := cronMacros[expr]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("schedule: cron %q: want 5 fields, got %d", expr, len(fields))
+	}
+
+	var sets [5]uint64
+	for i, f := range fields {
+		set, err := parseField(f, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: cron %q: %s: %w", expr, cronFields[i].name, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1 // Sunday is Sunday.
+	}
+
+	// Like Vixie cron, any day field starting with '*', such as "*/2",
+	// counts as a star for dayMatches.
+	return &cron{
+		expr:    expr,
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseField parses one field into a bit set.
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step %q", stepStr)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return 0, fmt.Errorf("bad value %q", loStr)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return 0, fmt.Errorf("bad value %q", hiStr)
+				}
+			} else if hasStep {
+				hi = max // 'a/n' means from a to the end, every n.
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// Next implements Schedule. It gives up (returning the zero Time) when
+// nothing matches within five years, e.g. for "0 0 30 2 *".
+func (c *cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches applies cron's rule for the two day fields: when both are
+// restricted, a day matching either one will do; when one starts with '*',
+// the day must match both, which for a bare '*' means the other one.
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// String returns the expression the schedule was parsed from.
+func (c *cron) String() string {
+	return c.expr
+}
@@ -0,0 +1,326 @@
+// Package schedule runs jobs periodically: at a fixed interval like the
+// watcher's 500ms ticker, on cron expressions, or either with jitter.
+//
+// A Scheduler follows the quit-channel idea of the stop-go-routine
+// example: it is given a quit channel, stops scheduling when it is closed,
+// and closes Done once the runs in progress have returned.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"advanced-concepts/concurrency/internal/conc"
+	"advanced-concepts/concurrency/tracing"
+)
+
+// Overlap decides what happens when a job is due while it is still running.
+type Overlap int
+
+const (
+	// Skip drops the run that is due.
+	Skip Overlap = iota
+
+	// Queue runs it as soon as the current run returns; several
+	// overlapping runs queue up one after the other.
+	Queue
+)
+
+// Job is a periodic piece of work.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Overlap  Overlap
+
+	// Run does one run of the job. quit is the Scheduler's: a long run
+	// should return early once it is closed.
+	Run func(quit <-chan struct{}) error
+}
+
+// JobStatus is a snapshot of a job, see Status.
+type JobStatus struct {
+	Name         string
+	Paused       bool
+	Running      bool
+	Runs         int // Completed runs.
+	Skipped      int // Runs dropped because of the Skip policy.
+	Queued       int // Runs waiting because of the Queue policy.
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastError    error     // Of the last run; nil if it succeeded.
+	NextRun      time.Time // Zero while paused, or when the schedule has ended.
+}
+
+// Errors returned by the Scheduler's methods.
+var (
+	ErrStopped    = errors.New("schedule: scheduler stopped")
+	ErrUnknownJob = errors.New("schedule: unknown job")
+	ErrDuplicate  = errors.New("schedule: job already added")
+)
+
+// job is the scheduler's record of a job.
+type job struct {
+	spec Job
+	wake chan struct{} // Pause or Resume was called.
+
+	// Guarded by Scheduler.mu.
+	paused       bool
+	running      bool
+	runs         int
+	skipped      int
+	queued       int
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastErr      error
+	next         time.Time
+}
+
+// clock is the time source of a Scheduler, so that tests can move time
+// themselves.
+type clock interface {
+	now() time.Time
+
+	// timer returns a channel that receives once d has elapsed, and a
+	// function that stops the timer.
+	timer(d time.Duration) (<-chan time.Time, func())
+}
+
+// realClock is the clock backed by the time package.
+type realClock struct{}
+
+func (realClock) now() time.Time { return time.Now() }
+
+func (realClock) timer(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTimer(d)
+	return t.C, func() { t.Stop() }
+}
+
+// Scheduler runs Jobs on their schedules.
+type Scheduler struct {
+	quit  <-chan struct{}
+	trace func(event, msg string, attrs ...slog.Attr)
+	clock clock
+
+	mu      sync.Mutex
+	jobs    []*job
+	byName  map[string]*job
+	stopped bool
+
+	wg   sync.WaitGroup // Job loops and runs.
+	done chan struct{}
+}
+
+// New returns a Scheduler that runs until quit is closed. t receives its
+// events and may be nil.
+func New(quit <-chan struct{}, t *tracing.Tracer) *Scheduler {
+	s := &Scheduler{
+		quit:   quit,
+		trace:  t.For(tracing.Indented("Scheduler")),
+		clock:  realClock{},
+		byName: make(map[string]*job),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		<-quit
+		s.mu.Lock()
+		s.stopped = true // No wg.Add after this: Wait below may start.
+		s.mu.Unlock()
+
+		s.wg.Wait()
+		close(s.done)
+	}()
+
+	return s
+}
+
+// Done is closed once quit is closed and every run in progress has returned.
+func (s *Scheduler) Done() <-chan struct{} {
+	return s.done
+}
+
+// Add schedules j; its first run is at j.Schedule.Next(now).
+func (s *Scheduler) Add(j Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return ErrStopped
+	}
+	if _, dup := s.byName[j.Name]; dup {
+		return fmt.Errorf("%w: %s", ErrDuplicate, j.Name)
+	}
+
+	jb := &job{spec: j, wake: make(chan struct{}, 1)}
+	s.jobs = append(s.jobs, jb)
+	s.byName[j.Name] = jb
+
+	s.wg.Add(1)
+	go s.loop(jb)
+	return nil
+}
+
+// Pause stops scheduling the named job. A run in progress goes on,
+// but runs queued behind it are dropped.
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume schedules the named job again, from now on.
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.byName[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	j.paused = paused
+	if paused {
+		j.queued = 0
+	}
+
+	select {
+	case j.wake <- struct{}{}:
+	default: // A wake-up is pending already.
+	}
+
+	event := "resume"
+	if paused {
+		event = "pause"
+	}
+	s.trace(event, event+" "+name, slog.String("job", name))
+	return nil
+}
+
+// Status returns a snapshot of every job, in the order they were added.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make([]JobStatus, len(s.jobs))
+	for i, j := range s.jobs {
+		status[i] = JobStatus{
+			Name:         j.spec.Name,
+			Paused:       j.paused,
+			Running:      j.running,
+			Runs:         j.runs,
+			Skipped:      j.skipped,
+			Queued:       j.queued,
+			LastRun:      j.lastRun,
+			LastDuration: j.lastDuration,
+			LastError:    j.lastErr,
+			NextRun:      j.next,
+		}
+	}
+	return status
+}
+
+// loop waits for the next run time of j, over and over, until quit.
+func (s *Scheduler) loop(j *job) {
+	defer s.wg.Done()
+
+	for {
+		s.mu.Lock()
+		var due <-chan time.Time // nil (never) while paused or finished.
+		stop := func() {}
+		j.next = time.Time{}
+		if !j.paused {
+			now := s.clock.now()
+			if next := j.spec.Schedule.Next(now); !next.IsZero() {
+				j.next = next
+				due, stop = s.clock.timer(next.Sub(now))
+			}
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-due:
+			s.fire(j)
+		case <-j.wake:
+			stop() // Paused or resumed: compute the next run again.
+		case <-s.quit:
+			stop()
+			return
+		}
+	}
+}
+
+// fire starts a run of j, or applies its Overlap policy.
+func (s *Scheduler) fire(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if j.paused || isClosed(s.quit) {
+		return
+	}
+	if j.running {
+		if j.spec.Overlap == Queue {
+			j.queued++
+			s.trace("queue", fmt.Sprintf("%s still running, run queued (%d waiting)", j.spec.Name, j.queued),
+				slog.String("job", j.spec.Name))
+		} else {
+			j.skipped++
+			s.trace("skip", j.spec.Name+" still running, run skipped", slog.String("job", j.spec.Name))
+		}
+		return
+	}
+
+	j.running = true
+	s.wg.Add(1) // Safe: the loop calling us holds the group open.
+	go s.run(j)
+}
+
+// run runs j, then the runs queued behind it.
+func (s *Scheduler) run(j *job) {
+	defer s.wg.Done()
+
+	for {
+		start := s.clock.now()
+		s.trace("run", "running "+j.spec.Name, slog.String("job", j.spec.Name))
+		err := call(j.spec.Run, s.quit)
+
+		s.mu.Lock()
+		j.runs++
+		j.lastRun = start
+		j.lastDuration = s.clock.now().Sub(start)
+		j.lastErr = err
+		if err != nil {
+			s.trace("error", fmt.Sprintf("%s failed: %v", j.spec.Name, err), slog.String("job", j.spec.Name))
+		}
+
+		if j.queued > 0 && !isClosed(s.quit) {
+			j.queued--
+			s.mu.Unlock()
+			continue
+		}
+		j.running = false
+		s.mu.Unlock()
+		return
+	}
+}
+
+// call runs a job, turning a panic into an error.
+func call(run func(quit <-chan struct{}) error, quit <-chan struct{}) (err error) {
+	if p := conc.Try(func() { err = run(quit) }); p != nil {
+		return fmt.Errorf("schedule: job panicked: %v", p.Value)
+	}
+	return err
+}
+
+// isClosed reports whether quit has been closed.
+func isClosed(quit <-chan struct{}) bool {
+	select {
+	case <-quit:
+		return true
+	default:
+		return false
+	}
+}
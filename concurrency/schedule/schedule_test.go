@@ -0,0 +1,417 @@
+package schedule
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCronParseErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"", "want 5 fields, got 0"},
+		{"* * * *", "want 5 fields, got 4"},
+		{"* * * * * *", "want 5 fields, got 6"},
+		{"@weekdays", "want 5 fields, got 1"},
+		{"60 * * * *", "minute: \"60\" out of range 0-59"},
+		{"* 24 * * *", "hour: \"24\" out of range 0-23"},
+		{"* * 0 * *", "day of month: \"0\" out of range 1-31"},
+		{"* * * 13 *", "month: \"13\" out of range 1-12"},
+		{"* * * * 8", "day of week: \"8\" out of range 0-7"},
+		{"5-1 * * * *", "minute: \"5-1\" out of range 0-59"},
+		{"*/0 * * * *", "minute: bad step \"0\""},
+		{"*/x * * * *", "minute: bad step \"x\""},
+		{"a * * * *", "minute: bad value \"a\""},
+		{"1-b * * * *", "minute: bad value \"b\""},
+		{"1,,2 * * * *", "minute: bad value \"\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Cron(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Cron(%q) = %v, want an error containing %q", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseField(t *testing.T) {
+	// bits returns the set holding vs.
+	bits := func(vs ...int) uint64 {
+		var set uint64
+		for _, v := range vs {
+			set |= 1 << v
+		}
+		return set
+	}
+
+	tests := []struct {
+		field    string
+		min, max int
+		want     uint64
+	}{
+		{"*", 0, 5, bits(0, 1, 2, 3, 4, 5)},
+		{"3", 0, 5, bits(3)},
+		{"1-3", 0, 5, bits(1, 2, 3)},
+		{"*/2", 0, 5, bits(0, 2, 4)},
+		{"1-5/2", 0, 5, bits(1, 3, 5)},
+		{"2/3", 0, 10, bits(2, 5, 8)},
+		{"1,4-5,*/10", 0, 20, bits(0, 1, 4, 5, 10, 20)},
+		{"*/2", 1, 12, bits(1, 3, 5, 7, 9, 11)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := parseField(tt.field, tt.min, tt.max)
+			if err != nil || got != tt.want {
+				t.Errorf("parseField(%q, %d, %d) = %b, %v; want %b", tt.field, tt.min, tt.max, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// at returns the given UTC time.
+func at(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"next minute", "* * * * *", at(2024, 3, 10, 12, 30), at(2024, 3, 10, 12, 31)},
+		{"seconds are dropped", "* * * * *", at(2024, 3, 10, 12, 30).Add(59 * time.Second), at(2024, 3, 10, 12, 31)},
+		{"step", "*/15 * * * *", at(2024, 3, 10, 12, 31), at(2024, 3, 10, 12, 45)},
+		{"range and list", "0 9-17/4,23 * * *", at(2024, 3, 10, 13, 0), at(2024, 3, 10, 17, 0)},
+		{"across midnight", "30 0 * * *", at(2024, 3, 10, 23, 59), at(2024, 3, 11, 0, 30)},
+		{"across a month end", "0 0 * * *", at(2024, 4, 30, 12, 0), at(2024, 5, 1, 0, 0)},
+		{"across a year end", "0 0 1 1 *", at(2024, 12, 31, 23, 59), at(2025, 1, 1, 0, 0)},
+		{"the 31st skips short months", "0 0 31 * *", at(2024, 4, 1, 0, 0), at(2024, 5, 31, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(2024, 3, 1, 0, 0), at(2028, 2, 29, 0, 0)},
+		{"Feb 28 to leap day", "0 0 * 2 *", at(2024, 2, 28, 12, 0), at(2024, 2, 29, 0, 0)},
+		{"Feb 28 to March", "0 0 * * *", at(2023, 2, 28, 12, 0), at(2023, 3, 1, 0, 0)},
+		{"never", "0 0 30 2 *", at(2024, 1, 1, 0, 0), time.Time{}},
+
+		{"@yearly", "@yearly", at(2024, 6, 1, 0, 0), at(2025, 1, 1, 0, 0)},
+		{"@monthly", "@monthly", at(2024, 6, 1, 0, 0), at(2024, 7, 1, 0, 0)},
+		{"@weekly", "@weekly", at(2024, 6, 1, 0, 0), at(2024, 6, 2, 0, 0)}, // A Saturday.
+		{"@daily", "@daily", at(2024, 6, 1, 0, 0), at(2024, 6, 2, 0, 0)},
+		{"@hourly", "@hourly", at(2024, 6, 1, 0, 0), at(2024, 6, 1, 1, 0)},
+
+		// 2024-06-01 is a Saturday; the 13th is a Thursday.
+		{"day of week only", "0 0 * * 1", at(2024, 6, 1, 0, 0), at(2024, 6, 3, 0, 0)},
+		{"Sunday as 7", "0 0 * * 7", at(2024, 6, 1, 0, 0), at(2024, 6, 2, 0, 0)},
+		{"day of month only", "0 0 13 * *", at(2024, 6, 1, 0, 0), at(2024, 6, 13, 0, 0)},
+		{"both restricted: either will do", "0 0 13 * 1", at(2024, 6, 1, 0, 0), at(2024, 6, 3, 0, 0)},
+		{"both restricted, the date first", "0 0 13 * 5", at(2024, 6, 8, 0, 0), at(2024, 6, 13, 0, 0)},
+		{"a starred step: both must match", "0 0 */2 * 2", at(2024, 6, 1, 0, 0), at(2024, 6, 11, 0, 0)},
+		{"a starred step on the weekday", "0 0 13 * */2", at(2024, 6, 1, 0, 0), at(2024, 6, 13, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Cron(tt.expr)
+			if err != nil {
+				t.Fatalf("Cron(%q): %v", tt.expr, err)
+			}
+			if got := s.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeClock is a clock whose time only moves when advance is called.
+type fakeClock struct {
+	mu     sync.Mutex
+	t      time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) timer(d time.Duration) (<-chan time.Time, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ft := &fakeTimer{at: c.t.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, ft)
+	return ft.c, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.remove(ft)
+	}
+}
+
+// remove drops ft from the pending timers. c.mu must be held.
+func (c *fakeClock) remove(ft *fakeTimer) {
+	for i, t := range c.timers {
+		if t == ft {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+// advance moves the clock forward by d and fires the timers now due.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.t = c.t.Add(d)
+	for _, ft := range append([]*fakeTimer(nil), c.timers...) {
+		if !ft.at.After(c.t) {
+			ft.c <- c.t
+			c.remove(ft)
+		}
+	}
+}
+
+// pending returns the number of timers waiting to fire.
+func (c *fakeClock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// eventually fails the test if cond doesn't become true within a second.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// harness is a Scheduler on a fake clock with one blocking job.
+type harness struct {
+	t       *testing.T
+	s       *Scheduler
+	clock   *fakeClock
+	quit    chan struct{}
+	started chan struct{} // Receives when a run starts.
+	release chan struct{} // A run returns when it receives.
+}
+
+const period = time.Minute
+
+func newHarness(t *testing.T, overlap Overlap) *harness {
+	h := &harness{
+		t:       t,
+		clock:   &fakeClock{t: at(2024, 6, 1, 0, 0)},
+		quit:    make(chan struct{}),
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+	}
+	h.s = New(h.quit, nil)
+	h.s.clock = h.clock
+	t.Cleanup(func() {
+		close(h.quit)
+		close(h.release)
+		<-h.s.Done()
+	})
+
+	err := h.s.Add(Job{
+		Name:     "job",
+		Schedule: Every(period),
+		Overlap:  overlap,
+		Run: func(quit <-chan struct{}) error {
+			h.started <- struct{}{}
+			select {
+			case <-h.release:
+			case <-quit:
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	h.waitTimer()
+	return h
+}
+
+// waitTimer waits until the job's loop has armed its timer.
+func (h *harness) waitTimer() {
+	h.t.Helper()
+	eventually(h.t, "the job's timer", func() bool { return h.clock.pending() == 1 })
+}
+
+// tick moves time to the job's next run and waits for the loop to arm
+// the timer of the one after.
+func (h *harness) tick() {
+	h.t.Helper()
+	h.clock.advance(period)
+	h.waitTimer()
+}
+
+func (h *harness) status() JobStatus {
+	return h.s.Status()[0]
+}
+
+func TestSchedulerSkip(t *testing.T) {
+	h := newHarness(t, Skip)
+
+	h.tick()
+	<-h.started
+	h.tick() // Due while the first run goes on: dropped.
+	h.tick()
+	if s := h.status(); !s.Running || s.Skipped != 2 || s.Queued != 0 || s.Runs != 0 {
+		t.Errorf("status %+v, want running with 2 skipped", s)
+	}
+
+	h.release <- struct{}{}
+	eventually(t, "the run to end", func() bool { return !h.status().Running })
+	if s := h.status(); s.Runs != 1 || s.Skipped != 2 {
+		t.Errorf("status %+v, want 1 run and 2 skipped", s)
+	}
+	if len(h.started) != 0 {
+		t.Error("a skipped run started")
+	}
+}
+
+func TestSchedulerQueue(t *testing.T) {
+	h := newHarness(t, Queue)
+
+	h.tick()
+	<-h.started
+	h.tick()
+	h.tick()
+	if s := h.status(); !s.Running || s.Queued != 2 || s.Skipped != 0 {
+		t.Errorf("status %+v, want running with 2 queued", s)
+	}
+
+	// The queued runs follow one after the other.
+	for want := 2; want >= 0; want-- {
+		h.release <- struct{}{}
+		if want > 0 {
+			<-h.started
+		}
+		eventually(t, "the queue to shrink", func() bool {
+			s := h.status()
+			return s.Queued == max(want-1, 0) && s.Runs == 3-want
+		})
+	}
+	if s := h.status(); s.Running || s.Runs != 3 {
+		t.Errorf("status %+v, want 3 runs, none running", s)
+	}
+}
+
+func TestSchedulerPauseResume(t *testing.T) {
+	h := newHarness(t, Queue)
+	start := h.clock.now()
+	if next := h.status().NextRun; !next.Equal(start.Add(period)) {
+		t.Errorf("NextRun = %v, want %v", next, start.Add(period))
+	}
+
+	h.tick()
+	<-h.started
+	h.tick() // Queued...
+	if err := h.s.Pause("job"); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	eventually(t, "the timer to stop", func() bool { return h.clock.pending() == 0 })
+	if s := h.status(); !s.Paused || s.Queued != 0 || !s.NextRun.IsZero() {
+		t.Errorf("status %+v, want paused with nothing queued or next", s)
+	}
+
+	// ...and dropped by the pause; nothing is due while paused.
+	h.release <- struct{}{}
+	eventually(t, "the run to end", func() bool { return !h.status().Running })
+	h.clock.advance(10 * period)
+	if s := h.status(); s.Runs != 1 || len(h.started) != 0 {
+		t.Errorf("status %+v, want the one run from before the pause", s)
+	}
+
+	// Resume schedules from now on.
+	if err := h.s.Resume("job"); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	h.waitTimer()
+	if s := h.status(); s.Paused || !s.NextRun.Equal(h.clock.now().Add(period)) {
+		t.Errorf("status %+v, want the next run one period from now", s)
+	}
+	h.tick()
+	<-h.started
+	h.release <- struct{}{}
+	eventually(t, "the second run", func() bool { return h.status().Runs == 2 })
+}
+
+func TestSchedulerRunRecords(t *testing.T) {
+	quit := make(chan struct{})
+	clock := &fakeClock{t: at(2024, 6, 1, 0, 0)}
+	s := New(quit, nil)
+	s.clock = clock
+
+	errBoom := errors.New("boom")
+	fail := true
+	s.Add(Job{Name: "flaky", Schedule: Every(period), Run: func(<-chan struct{}) error {
+		clock.advance(3 * time.Second) // The run takes 3 virtual seconds.
+		if fail {
+			return errBoom
+		}
+		panic("bug")
+	}})
+	eventually(t, "the timer", func() bool { return clock.pending() == 1 })
+
+	clock.advance(period)
+	eventually(t, "the first run", func() bool { return s.Status()[0].Runs == 1 })
+	st := s.Status()[0]
+	if !errors.Is(st.LastError, errBoom) || st.LastDuration != 3*time.Second || !st.LastRun.Equal(at(2024, 6, 1, 0, 1)) {
+		t.Errorf("status %+v, want boom after 3s, at 00:01", st)
+	}
+
+	eventually(t, "the timer", func() bool { return clock.pending() == 1 })
+	fail = false
+	clock.advance(period)
+	eventually(t, "the second run", func() bool { return s.Status()[0].Runs == 2 })
+	if err := s.Status()[0].LastError; err == nil || !strings.Contains(err.Error(), "panicked: bug") {
+		t.Errorf("LastError = %v, want the panic", err)
+	}
+
+	close(quit)
+	<-s.Done()
+}
+
+func TestSchedulerErrors(t *testing.T) {
+	quit := make(chan struct{})
+	s := New(quit, nil)
+	job := Job{Name: "job", Schedule: Every(time.Hour), Run: func(<-chan struct{}) error { return nil }}
+
+	if err := s.Add(job); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := s.Add(job); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("second Add = %v, want %v", err, ErrDuplicate)
+	}
+	if err := s.Pause("ghost"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Pause(ghost) = %v, want %v", err, ErrUnknownJob)
+	}
+	if err := s.Resume("ghost"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Resume(ghost) = %v, want %v", err, ErrUnknownJob)
+	}
+
+	close(quit)
+	<-s.Done()
+	job.Name = "late"
+	if err := s.Add(job); !errors.Is(err, ErrStopped) {
+		t.Errorf("Add after quit = %v, want %v", err, ErrStopped)
+	}
+}
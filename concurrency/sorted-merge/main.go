@@ -0,0 +1,67 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"advanced-concepts/concurrency/channels"
+)
+
+// --- Sorted Producers ---
+
+// record is a value with the producer it came from, to check stability.
+type record struct {
+	key, src int
+}
+
+func byKey(a, b record) int { return cmp.Compare(a.key, b.key) }
+
+// produce sends vs in order, sleeping up to maxDelay before each send,
+// so that inputs interleave and close at different times. It gives up
+// when ctx is cancelled.
+func produce(ctx context.Context, r *rand.Rand, vs []record, maxDelay time.Duration) <-chan record {
+	delays := make([]time.Duration, len(vs))
+	for i := range delays {
+		delays[i] = time.Duration(r.Int64N(int64(maxDelay) + 1))
+	}
+
+	ch := make(chan record)
+	go func() {
+		defer close(ch)
+		for i, v := range vs {
+			time.Sleep(delays[i])
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// --- Main Function ---
+
+func main() {
+	fmt.Println("Merging three sorted timestamp streams")
+	r := rand.New(rand.NewPCG(1, 2))
+	streams := [][]record{
+		{{1, 0}, {4, 0}, {9, 0}},
+		{{2, 1}, {3, 1}, {4, 1}, {10, 1}, {11, 1}},
+		{}, // Closes right away.
+	}
+	var chans []<-chan record
+	for _, vs := range streams {
+		chans = append(chans, produce(context.Background(), r, vs, time.Millisecond))
+	}
+	for v := range channels.MergeSorted(context.Background(), byKey, chans...) {
+		fmt.Printf("   t=%-2d from stream %d\n", v.key, v.src)
+	}
+
+	// TestMergeSortedProperty in concurrency/channels checks the merge
+	// against a stable sort on random inputs, and TestMergeSortedCancelled
+	// that a silent input only holds the output back until ctx is done.
+}
@@ -1,105 +1,399 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
 	"sync"
 	"time"
+
+	"advanced-concepts/concurrency/health"
+	"advanced-concepts/concurrency/schedule"
+	"advanced-concepts/concurrency/tracing"
 )
 
+// mainRole prints as this example always did: "Main: ", without brackets.
+var mainRole = tracing.Role{Name: "Main", Prefix: "Main: "}
+
 // watcher holds resources that need graceful cleanup.
 type watcher struct {
-	wg   sync.WaitGroup
-	quit chan struct{} // A channel to signal the goroutine to stop
+	name    string
+	cleanup time.Duration // How long the simulated cleanup takes.
+
+	quit     chan struct{} // A channel to signal the goroutine to stop
+	quitOnce sync.Once     // Closing 'quit' twice would panic.
+	done     chan struct{} // Closed by the goroutine when it has finished.
+
+	jobs  *schedule.Scheduler  // The "work", run periodically until 'quit'.
+	beats map[string]monitored // Per job name.
+	trace *tracing.Tracer
+}
+
+// monitored is the health of one job: a heartbeat that beats after every
+// run, with a stall threshold that follows the job's schedule.
+type monitored struct {
+	beat     *health.Heartbeat
+	schedule schedule.Schedule
+}
+
+// missedRuns is how many scheduled runs of a job may go by without one
+// of them finishing before the job counts as stalled.
+const missedRuns = 3
+
+// stallAfter is how long a job on s may go without finishing a run,
+// counting from now: until its missedRuns-th next run is due. That is
+// 1.5s for a job every 500ms, and three hours for an @hourly one.
+// It returns 0 if the schedule ends before that.
+func stallAfter(s schedule.Schedule, now time.Time) time.Duration {
+	next := now
+	for i := 0; i < missedRuns; i++ {
+		if next = s.Next(next); next.IsZero() {
+			return 0
+		}
+	}
+	return next.Sub(now)
+}
+
+// expect moves the stall threshold of m along with its schedule.
+// A job whose schedule has ended can't stall any more.
+func (m monitored) expect() {
+	d := stallAfter(m.schedule, time.Now())
+	if d == 0 {
+		m.beat.Pause()
+		return
+	}
+	m.beat.Expect(d)
 }
 
 // newWatcher creates a watcher, starts its goroutine, and returns it.
-func newWatcher() *watcher {
+// Its progress is reported to t and its health to reg, which may be nil.
+// Without jobs, it "watches" every 500ms, as it always did.
+func newWatcher(name string, cleanup time.Duration, t *tracing.Tracer, reg *health.Registry, jobs ...schedule.Job) *watcher {
+	if len(jobs) == 0 {
+		trace := t.For(tracing.Indented("Goroutine " + name))
+		jobs = []schedule.Job{{
+			Name:     "watch",
+			Schedule: schedule.Every(500 * time.Millisecond),
+			Run: func(<-chan struct{}) error {
+				// This is the "work"
+				trace("tick", "...doing work (watching)...")
+				return nil
+			},
+		}}
+	}
+
 	w := &watcher{
-		// Make the 'quit' channel
-		quit: make(chan struct{}),
+		name:    name,
+		cleanup: cleanup,
+		beats:   make(map[string]monitored),
+		// Make the 'quit' and 'done' channels *before* starting the
+		// goroutine, so that Shutdown can never see them nil.
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+		trace: t,
 	}
 
-	// Add 1 to the WaitGroup *before* starting the goroutine.
-	// This prevents a race condition where close() might run before Add().
-	w.wg.Add(1)
+	// The work runs on a scheduler that stops when 'quit' is closed.
+	// Every job has its own heartbeat, so that a hung job can't hide
+	// behind the runs of the others, and every run that completes is
+	// a beat: a run that hangs stops them.
+	w.jobs = schedule.New(w.quit, t)
+	for _, j := range jobs {
+		d := stallAfter(j.Schedule, time.Now())
+		m := monitored{beat: reg.Register(name+"/"+j.Name, d), schedule: j.Schedule}
+		if d == 0 {
+			m.beat.Pause() // The schedule is over before it started.
+		}
+		w.beats[j.Name] = m
+
+		run := j.Run
+		j.Run = func(quit <-chan struct{}) error {
+			defer func() {
+				m.expect()
+				m.beat.Beat()
+			}()
+			return run(quit)
+		}
+		if err := w.jobs.Add(j); err != nil {
+			t.Event(tracing.Indented("Goroutine "+name), "error", err.Error())
+		}
+	}
 
 	// Start the background goroutine
 	go w.watch()
 
-	fmt.Println("Main: newWatcher() created and goroutine started.")
+	w.trace.Event(mainRole, "start", fmt.Sprintf("newWatcher(%q) created and goroutine started.", name))
 	return w
 }
 
 // watch is the background goroutine's main loop.
 // It listens for work or a quit signal.
 func (w *watcher) watch() {
-	// Defer wg.Done() to signal that this goroutine has
-	// *officially* finished when the function returns.
-	defer w.wg.Done()
+	// Defer close(done) to signal that this goroutine has
+	// *officially* finished when the function returns. Unlike
+	// wg.Done(), a closed channel can be waited for in a select,
+	// next to a deadline.
+	defer close(w.done)
 
-	fmt.Println("   [Goroutine]: watch() started.")
+	trace := w.trace.For(tracing.Indented("Goroutine " + w.name))
+	trace("start", "watch() started.")
 
-	// Simulate doing work every 500ms
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop() // Clean up the ticker's resources
+	for _, m := range w.beats {
+		m.beat.SetReady(true)
+		defer m.beat.Stop()
+	}
 
-	for {
-		select {
-		case <-ticker.C:
-			// This is the "work"
-			fmt.Println("   [Goroutine]: ...doing work (watching)...")
+	// We wait for a stop signal from Shutdown().
+	<-w.quit
+	for _, m := range w.beats {
+		m.beat.SetReady(false)
+	}
+	trace("quit", "Quit signal received.", tracing.Channel("quit"))
 
-		case <-w.quit:
-			// We received a stop signal from the close() method.
-			fmt.Println("   [Goroutine]: Quit signal received.")
+	// Let the runs in progress see 'quit' and return.
+	<-w.jobs.Done()
 
-			// --- This is the critical cleanup phase ---
-			fmt.Println("   [Goroutine]: Cleaning up resources (e.g., closing DB conn)...")
-			time.Sleep(250 * time.Millisecond) // Simulate time taken for cleanup
-			fmt.Println("   [Goroutine]: Cleanup complete. Exiting.")
+	// --- This is the critical cleanup phase ---
+	trace("cleanup", "Cleaning up resources (e.g., closing DB conn)...")
+	time.Sleep(w.cleanup) // Simulate time taken for cleanup
+	trace("exit", "Cleanup complete. Exiting.")
 
-			// Return from the function, which will trigger the 'defer wg.Done()'
-			return
-		}
+	// Return from the function, which will trigger the 'defer close(w.done)'
+}
+
+// pause stops running a job until resume, without it counting as stalled.
+func (w *watcher) pause(job string) error {
+	if err := w.jobs.Pause(job); err != nil {
+		return err
 	}
+	w.beats[job].beat.Pause()
+	return nil
 }
 
-// close is the public method to shut down the watcher.
-// It will block until the 'watch' goroutine is fully stopped.
-func (w *watcher) close() {
-	fmt.Println("Main: Calling w.close()...")
+// resume runs a paused job again. Its stall threshold starts over from now.
+func (w *watcher) resume(job string) error {
+	if err := w.jobs.Resume(job); err != nil {
+		return err
+	}
+	m := w.beats[job]
+	m.beat.Resume()
+	m.expect()
+	return nil
+}
+
+// ShutdownError names the watchers that were still cleaning up when the
+// deadline of Shutdown passed. They have been abandoned: their goroutines
+// go on until their cleanup finishes, but nobody waits for them any more.
+type ShutdownError struct {
+	Workers []string
+	Err     error // Why we stopped waiting: the context's error.
+}
+
+// Implement the error interface for ShutdownError.
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("shutdown: %d worker(s) failed to stop in time (%s): %v",
+		len(e.Workers), strings.Join(e.Workers, ", "), e.Err)
+}
 
-	// Signal the goroutine to stop by closing the quit channel.
+// Unwrap makes errors.Is(err, context.DeadlineExceeded) work.
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// Shutdown signals the goroutine to stop and waits for its cleanup, but
+// not past the deadline of ctx: then it gives up with a *ShutdownError.
+// It is idempotent and safe to call concurrently: every call sends (at
+// most) the same signal and waits for the same goroutine.
+func (w *watcher) Shutdown(ctx context.Context) error {
+	trace := w.trace.For(mainRole)
+	trace("shutdown", fmt.Sprintf("Shutting down %s...", w.name))
+
+	// Signal the goroutine to stop by closing the quit channel, once.
 	// All receivers on this channel will get a "zero" value.
-	close(w.quit)
+	w.quitOnce.Do(func() {
+		close(w.quit)
+	})
 
 	// --- This is the key to the pattern ---
-	// Wait for the goroutine to call 'wg.Done()'.
-	// This *blocks* 'close()' (and thus 'main') until cleanup is done.
-	fmt.Println("Main: Waiting for goroutine to finish...")
-	w.wg.Wait()
+	// Wait for the goroutine to close 'done', or for the deadline.
+	select {
+	case <-w.done:
+		trace("closed", fmt.Sprintf("Watcher %s fully closed.", w.name))
+		return nil
+	case <-ctx.Done():
+		trace("abandon", fmt.Sprintf("Watcher %s still cleaning up; abandoning it.", w.name))
+		return &ShutdownError{Workers: []string{w.name}, Err: ctx.Err()}
+	}
+}
 
-	fmt.Println("Main: Watcher fully closed.")
+// close is the original, unbounded shutdown: it blocks until the
+// 'watch' goroutine is fully stopped, however long that takes.
+func (w *watcher) close() {
+	w.Shutdown(context.Background())
+}
+
+// shutdownAll shuts the watchers down concurrently, so that they all
+// share the deadline of ctx, and names every one that missed it.
+func shutdownAll(ctx context.Context, ws ...*watcher) error {
+	errs := make([]error, len(ws))
+	var wg sync.WaitGroup
+	for i, w := range ws {
+		wg.Add(1)
+		go func(i int, w *watcher) {
+			defer wg.Done()
+			errs[i] = w.Shutdown(ctx)
+		}(i, w)
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, ws[i].name)
+		}
+	}
+	if len(failed) > 0 {
+		return &ShutdownError{Workers: failed, Err: ctx.Err()}
+	}
+	return nil
+}
+
+// --- Health Probes ---
+
+// probe fetches a health endpoint and traces its verdict per worker.
+func probe(trace func(string, string, ...slog.Attr), base, path string) {
+	resp, err := http.Get(base + path)
+	if err != nil {
+		trace("probe", fmt.Sprintf("GET %s: %v", path, err))
+		return
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status  string          `json:"status"`
+		Workers []health.Status `json:"workers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		trace("probe", fmt.Sprintf("GET %s: %v", path, err))
+		return
+	}
+
+	var workers []string
+	for _, s := range body.Workers {
+		workers = append(workers, fmt.Sprintf("%s(alive=%v ready=%v paused=%v beats=%d)",
+			s.Name, s.Alive, s.Ready, s.Paused, s.Beats))
+	}
+	trace("probe", fmt.Sprintf("GET %s -> %d %s: %s", path, resp.StatusCode, body.Status, strings.Join(workers, " ")),
+		slog.Int("code", resp.StatusCode))
 }
 
 // --- Main Application ---
 
 func main() {
-	fmt.Println("Main: Application starting...")
+	format := flag.String("trace", "text", "trace output: text, json or off")
+	addr := flag.String("health", "127.0.0.1:0", "address of the liveness/readiness endpoint")
+	flag.Parse()
+
+	t, err := tracing.ForFormat(*format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	trace := t.For(mainRole)
+
+	trace("start", "Application starting...")
+
+	// --- Health Endpoint and Stall Detector ---
+	reg := health.NewRegistry(t)
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	srv := &http.Server{Handler: reg.Handler()}
+	go srv.Serve(ln)
+	defer srv.Close()
+	base := "http://" + ln.Addr().String()
+	trace("health", "Health endpoint on "+base+"/livez and /readyz")
+
+	stopDetector := make(chan struct{})
+	defer close(stopDetector)
+	go reg.Detect(stopDetector, 250*time.Millisecond, nil)
+
+	// Create the watchers (which starts their goroutines). The DB's
+	// cleanup hangs for far longer than we are willing to wait.
+	db := newWatcher("db", 5*time.Second, t, reg)
+	// The cache's hourly snapshot doesn't run during the demo, and
+	// doesn't count as stalled either: its threshold is three hours.
+	hourly, err := schedule.Cron("@hourly")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	cache := newWatcher("cache", 250*time.Millisecond, t, reg, schedule.Job{
+		Name:     "evict",
+		Schedule: schedule.WithJitter(schedule.Every(300*time.Millisecond), 100*time.Millisecond),
+		Run: func(<-chan struct{}) error {
+			t.Event(tracing.Indented("Goroutine cache"), "evict", "...evicting stale entries...")
+			return nil
+		},
+	}, schedule.Job{
+		Name:     "snapshot",
+		Schedule: hourly,
+		Run: func(<-chan struct{}) error {
+			t.Event(tracing.Indented("Goroutine cache"), "snapshot", "...writing a snapshot...")
+			return nil
+		},
+	})
+	// The queue's first run hangs (a lost connection without a timeout):
+	// its goroutine is still there, but it stops making progress.
+	queue := newWatcher("queue", 50*time.Millisecond, t, reg, schedule.Job{
+		Name:     "drain",
+		Schedule: schedule.Every(500 * time.Millisecond),
+		Run: func(quit <-chan struct{}) error {
+			t.Event(tracing.Indented("Goroutine queue"), "drain", "...draining, waiting for the broker...")
+			<-quit
+			return nil
+		},
+	})
 
-	// Create the watcher (which starts the goroutine)
-	w := newWatcher()
+	// Simulate the main application running for a short time. Halfway,
+	// eviction is paused: a paused job is idle, not stalled.
+	trace("run", "Application running for 2 seconds...")
+	time.Sleep(time.Second)
+	if err := cache.pause("evict"); err != nil {
+		trace("error", err.Error())
+	}
+	time.Sleep(time.Second)
+
+	probe(trace, base, "/livez")
+	probe(trace, base, "/readyz")
+	if err := cache.resume("evict"); err != nil {
+		trace("error", err.Error())
+	}
 
-	// This is the solution! We use 'defer' to call 'close()'.
-	// This guarantees that close() is called before main exits.
-	// Because w.close() *blocks*, 'main' cannot exit until
-	// the goroutine is confirmed to be finished.
-	defer w.close()
+	trace("shutdown", "Application shutting down, giving cleanup 500ms.")
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
 
-	// Simulate the main application running for a short time
-	fmt.Println("Main: Application running for 2 seconds...")
-	time.Sleep(2 * time.Second)
+	// Shutting down twice, and from several goroutines, is harmless:
+	// a second close(w.quit) would have panicked.
+	go cache.Shutdown(ctx)
+	err = shutdownAll(ctx, db, cache, queue)
+	queue.close()
+	probe(trace, base, "/readyz")
 
-	fmt.Println("Main: Application shutting down.")
-	// The 'defer w.close()' will execute here
+	var se *ShutdownError
+	if errors.As(err, &se) {
+		trace("result", fmt.Sprintf("Abandoned %v (deadline exceeded: %v).",
+			se.Workers, errors.Is(err, context.DeadlineExceeded)))
+	}
+	trace("exit", fmt.Sprintf("Exiting with err=%v", err))
 }
@@ -0,0 +1,450 @@
+// Package supervisor restarts watcher-style workers when they fail.
+//
+// The watcher of the stop-go-routine example stops its goroutine cleanly,
+// but nothing brings it back if watch() panics or returns early. A
+// Supervisor runs many such workers and restarts them following one of
+// Erlang's strategies, with backoff between restarts and a limit on how
+// often it is willing to restart before giving up.
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"advanced-concepts/concurrency/internal/conc"
+	"advanced-concepts/concurrency/tracing"
+)
+
+// Worker is the body of a watcher-style goroutine: it works until quit is
+// closed, cleans up and returns nil. Returning an error or panicking is a
+// failure, and so is returning nil before quit is closed, except for a
+// Transient child, which is then done.
+type Worker func(quit <-chan struct{}) error
+
+// Strategy decides which children are restarted when one fails.
+type Strategy int
+
+const (
+	// OneForOne restarts only the failed child.
+	OneForOne Strategy = iota
+
+	// OneForAll stops every other child and restarts them all, for
+	// children that can't work without each other.
+	OneForAll
+
+	// RestForOne stops and restarts the failed child and the children
+	// started after it, which are assumed to depend on it.
+	RestForOne
+)
+
+// Restart decides whether a child is restarted at all.
+type Restart int
+
+const (
+	// Permanent children are always restarted.
+	Permanent Restart = iota
+
+	// Transient children are restarted only when they fail with an error
+	// or a panic; returning nil means they are done.
+	Transient
+
+	// Temporary children are never restarted.
+	Temporary
+)
+
+// Spec describes a child.
+type Spec struct {
+	Name    string
+	Run     Worker
+	Restart Restart
+}
+
+// Config configures a Supervisor.
+type Config struct {
+	Strategy Strategy
+
+	// MaxRestarts within Period is the restart intensity: one restart more
+	// and the supervisor stops every child and gives up with
+	// ErrTooManyRestarts. They default to 3 restarts in 5 seconds.
+	MaxRestarts int
+	Period      time.Duration
+
+	// Backoff is the delay before restarting a child that keeps failing.
+	// The count starts over once a child has run for a whole Period.
+	// It defaults to 10ms doubling up to 1s, with 20% jitter.
+	Backoff Backoff
+
+	// Trace receives the supervisor's events; nil means none.
+	Trace *tracing.Tracer
+}
+
+// Backoff computes the delay before a restart, the same way the backoff of
+// a pool.RetryPolicy does: Base, then Base*Multiplier, ... capped at Max,
+// with a random Jitter share removed.
+type Backoff = conc.Backoff
+
+// ErrTooManyRestarts is returned by Wait once the restart intensity
+// has been exceeded.
+var ErrTooManyRestarts = errors.New("supervisor: too many restarts")
+
+// PanicError is a panic raised by a child and recovered by the supervisor.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Implement the error interface for PanicError.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("supervisor: child panicked: %v", e.Value)
+}
+
+// errEarlyReturn is the failure of a Worker that returned nil before quit.
+var errEarlyReturn = errors.New("supervisor: child returned before being stopped")
+
+// State is where a child is in its life cycle.
+type State int
+
+const (
+	Running    State = iota
+	Restarting       // Waiting for its backoff to elapse.
+	Stopped          // Stopped by the supervisor, or done for good.
+	Failed           // Failed and not restarted (Temporary, or the supervisor gave up).
+)
+
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Restarting:
+		return "restarting"
+	case Stopped:
+		return "stopped"
+	case Failed:
+		return "failed"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// ChildStatus is a snapshot of one child, see Status.
+type ChildStatus struct {
+	Name      string
+	State     State
+	Restarts  int       // Times it was restarted, for any reason.
+	LastError error     // Why it last failed, or nil.
+	Since     time.Time // When it entered its current state.
+}
+
+// child is the supervisor's record of one child.
+type child struct {
+	spec      Spec
+	state     State
+	restarts  int
+	failures  int // Consecutive failures, for the backoff.
+	lastErr   error
+	since     time.Time
+	run       *run // The current run, or nil.
+	startedAt time.Time
+}
+
+// run is one execution of a child's Worker.
+type run struct {
+	quit chan struct{}
+	done chan struct{} // Closed when the Worker has returned.
+	err  error         // Set before done is closed.
+}
+
+// exit reports that the run of child idx has ended.
+type exit struct {
+	idx int
+	run *run
+}
+
+// restart asks the loop to start a group of children after a backoff.
+type restart struct {
+	group []int
+	gen   int // Matches Supervisor.gen when the restart was scheduled.
+}
+
+// Supervisor runs children and restarts them when they fail.
+type Supervisor struct {
+	cfg   Config
+	trace func(event, msg string, attrs ...slog.Attr)
+
+	mu       sync.Mutex // Guards children for Status.
+	children []*child
+
+	exits    chan exit
+	restarts chan restart
+	gen      int         // Bumped when scheduled restarts become obsolete.
+	history  []time.Time // Recent restarts, for the intensity limit.
+
+	stopOnce sync.Once
+	quit     chan struct{}
+	done     chan struct{}
+	err      error
+}
+
+// New starts the children in order and supervises them until Stop is
+// called or the restart intensity is exceeded.
+func New(cfg Config, specs ...Spec) *Supervisor {
+	if cfg.MaxRestarts < 1 {
+		cfg.MaxRestarts = 3
+	}
+	if cfg.Period <= 0 {
+		cfg.Period = 5 * time.Second
+	}
+	if cfg.Backoff == (Backoff{}) {
+		cfg.Backoff = Backoff{Base: 10 * time.Millisecond, Max: time.Second, Jitter: 0.2}
+	}
+
+	s := &Supervisor{
+		cfg:      cfg,
+		trace:    cfg.Trace.For(tracing.Indented("Supervisor")),
+		children: make([]*child, len(specs)),
+		exits:    make(chan exit),
+		restarts: make(chan restart),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for i, spec := range specs {
+		s.children[i] = &child{spec: spec}
+	}
+
+	go s.loop()
+	return s
+}
+
+// Stop stops every child, in reverse order, and waits for them.
+// It returns the same error as Wait and is safe to call more than once.
+func (s *Supervisor) Stop() error {
+	s.stopOnce.Do(func() {
+		close(s.quit)
+	})
+	return s.Wait()
+}
+
+// Wait blocks until the supervisor has stopped and returns
+// ErrTooManyRestarts if it gave up, or nil if it was stopped.
+func (s *Supervisor) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// Status returns a snapshot of every child, in start order.
+func (s *Supervisor) Status() []ChildStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make([]ChildStatus, len(s.children))
+	for i, c := range s.children {
+		status[i] = ChildStatus{
+			Name:      c.spec.Name,
+			State:     c.state,
+			Restarts:  c.restarts,
+			LastError: c.lastErr,
+			Since:     c.since,
+		}
+	}
+	return status
+}
+
+// loop owns the children: it is the only goroutine that starts, stops
+// and restarts them.
+func (s *Supervisor) loop() {
+	defer close(s.done)
+
+	for i := range s.children {
+		s.start(i)
+	}
+
+	for {
+		select {
+		case e := <-s.exits:
+			c := s.children[e.idx]
+			if e.run != c.run {
+				continue // A run the supervisor stopped on purpose.
+			}
+			if err := s.exited(e.idx, e.run.err); err != nil {
+				s.err = err
+				s.stopAll(Failed)
+				return
+			}
+
+		case r := <-s.restarts:
+			if r.gen != s.gen {
+				continue // A wider restart replaced this one.
+			}
+			for _, i := range r.group {
+				if s.children[i].state == Restarting {
+					s.setRestarts(i)
+					s.start(i)
+				}
+			}
+
+		case <-s.quit:
+			s.gen++
+			s.stopAll(Stopped)
+			return
+		}
+	}
+}
+
+// exited handles the end of a child's current run. It returns an error
+// if the supervisor has to give up.
+func (s *Supervisor) exited(idx int, err error) error {
+	c := s.children[idx]
+	if err == nil && c.spec.Restart == Transient {
+		s.trace("done", c.spec.Name+" is done", slog.String("child", c.spec.Name))
+		s.setState(idx, Stopped, c.lastErr)
+		return nil
+	}
+	if err == nil {
+		err = errEarlyReturn // Only the supervisor may stop a Permanent child.
+	}
+	s.trace("exit", fmt.Sprintf("%s failed: %v", c.spec.Name, err), slog.String("child", c.spec.Name))
+
+	if c.spec.Restart == Temporary {
+		s.setState(idx, Failed, err)
+		return nil
+	}
+
+	// Too many restarts: give up.
+	now := time.Now()
+	recent := s.history[:0]
+	for _, t := range s.history {
+		if now.Sub(t) < s.cfg.Period {
+			recent = append(recent, t)
+		}
+	}
+	s.history = append(recent, now)
+	if len(s.history) > s.cfg.MaxRestarts {
+		s.setState(idx, Failed, err)
+		s.trace("give-up", fmt.Sprintf("more than %d restarts in %v, giving up", s.cfg.MaxRestarts, s.cfg.Period))
+		return fmt.Errorf("%w: child %s: %w", ErrTooManyRestarts, c.spec.Name, err)
+	}
+
+	// The children to restart, per strategy.
+	group := []int{idx}
+	switch s.cfg.Strategy {
+	case OneForAll:
+		group = group[:0]
+		for i := range s.children {
+			group = append(group, i)
+		}
+	case RestForOne:
+		group = group[:0]
+		for i := idx; i < len(s.children); i++ {
+			group = append(group, i)
+		}
+	}
+
+	// Stop the rest of the group, last started first.
+	for k := len(group) - 1; k >= 0; k-- {
+		if i := group[k]; i != idx && s.children[i].state == Running {
+			s.stopChild(i)
+		}
+	}
+	for _, i := range group {
+		if i == idx || s.children[i].spec.Restart != Temporary {
+			s.setState(i, Restarting, s.children[i].lastErr)
+		}
+	}
+	s.setState(idx, Restarting, err)
+
+	// Back off more and more for a child that keeps failing.
+	if now.Sub(c.startedAt) >= s.cfg.Period {
+		c.failures = 0
+	}
+	c.failures++
+	delay := s.cfg.Backoff.Delay(c.failures, rand.Float64())
+	s.trace("backoff", fmt.Sprintf("restarting %d child(ren) in %v", len(group), delay.Round(time.Millisecond)),
+		slog.String("child", c.spec.Name), slog.Duration("delay", delay))
+
+	if s.cfg.Strategy != OneForOne {
+		s.gen++ // A pending restart of an overlapping group is now obsolete.
+	}
+	r := restart{group: group, gen: s.gen}
+	time.AfterFunc(delay, func() {
+		select {
+		case s.restarts <- r:
+		case <-s.done:
+		}
+	})
+	return nil
+}
+
+// start runs child i in its own goroutine.
+func (s *Supervisor) start(i int) {
+	c := s.children[i]
+	r := &run{quit: make(chan struct{}), done: make(chan struct{})}
+	c.run = r
+	c.startedAt = time.Now()
+	s.setState(i, Running, c.lastErr)
+	s.trace("start", "starting "+c.spec.Name, slog.String("child", c.spec.Name))
+
+	go func() {
+		r.err = call(c.spec.Run, r.quit)
+		close(r.done)
+
+		// Nobody may be listening any more: the loop stopped this run on
+		// purpose, or has returned.
+		select {
+		case s.exits <- exit{idx: i, run: r}:
+		case <-s.done:
+		}
+	}()
+}
+
+// stopChild closes the quit channel of child i and waits for it.
+func (s *Supervisor) stopChild(i int) {
+	c := s.children[i]
+	r := c.run
+	c.run = nil
+	close(r.quit)
+	<-r.done
+	s.setState(i, Stopped, c.lastErr)
+	s.trace("stop", "stopped "+c.spec.Name, slog.String("child", c.spec.Name))
+}
+
+// stopAll stops every running child, last started first, and marks
+// the others with 'state'.
+func (s *Supervisor) stopAll(state State) {
+	for i := len(s.children) - 1; i >= 0; i-- {
+		if s.children[i].state == Running {
+			s.stopChild(i)
+		} else if s.children[i].state == Restarting {
+			s.setState(i, state, s.children[i].lastErr)
+		}
+	}
+}
+
+// setState records the state of child i for Status.
+func (s *Supervisor) setState(i int, state State, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.children[i]
+	c.state = state
+	c.lastErr = err
+	c.since = time.Now()
+}
+
+// setRestarts counts a restart of child i for Status.
+func (s *Supervisor) setRestarts(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.children[i].restarts++
+}
+
+// call runs w, turning a panic into a *PanicError.
+func call(w Worker, quit <-chan struct{}) (err error) {
+	if p := conc.Try(func() { err = w(quit) }); p != nil {
+		return &PanicError{Value: p.Value, Stack: p.Stack}
+	}
+	return err
+}
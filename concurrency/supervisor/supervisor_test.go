@@ -0,0 +1,308 @@
+package supervisor_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"advanced-concepts/concurrency/supervisor"
+	"advanced-concepts/concurrency/tracing"
+)
+
+// errPanic makes a worker panic instead of returning.
+var errPanic = errors.New("panic")
+
+// fleet holds workers that fail on command and records their starts.
+type fleet struct {
+	mu     sync.Mutex
+	runs   map[string]int // Runs started per name, counted by the workers.
+	starts []string       // Names, in the order the supervisor started them.
+	fail   map[string]chan error
+}
+
+func newFleet(names ...string) *fleet {
+	f := &fleet{runs: make(map[string]int), fail: make(map[string]chan error)}
+	for _, name := range names {
+		f.fail[name] = make(chan error)
+	}
+	return f
+}
+
+// Write implements io.Writer for the supervisor's text trace, picking the
+// start order out of its "starting <name>" events.
+func (f *fleet) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if name, ok := strings.CutPrefix(strings.TrimSpace(string(p)), "[Supervisor]: starting "); ok {
+		f.starts = append(f.starts, name)
+	}
+	return len(p), nil
+}
+
+// config returns cfg tracing to f.
+func (f *fleet) config(cfg supervisor.Config) supervisor.Config {
+	cfg.Trace = tracing.NewText(f)
+	return cfg
+}
+
+// worker works until quit, or until it is told to fail: with an error,
+// with a panic for errPanic, or by returning nil early for a nil error.
+func (f *fleet) worker(name string) supervisor.Worker {
+	return func(quit <-chan struct{}) error {
+		f.mu.Lock()
+		f.runs[name]++
+		f.mu.Unlock()
+
+		select {
+		case <-quit:
+			return nil
+		case err := <-f.fail[name]:
+			if err == errPanic {
+				panic("boom")
+			}
+			return err
+		}
+	}
+}
+
+// spec returns the Spec of the named worker.
+func (f *fleet) spec(name string, restart supervisor.Restart) supervisor.Spec {
+	return supervisor.Spec{Name: name, Run: f.worker(name), Restart: restart}
+}
+
+// permanent returns the Specs of Permanent workers named names.
+func (f *fleet) permanent(names ...string) []supervisor.Spec {
+	var specs []supervisor.Spec
+	for _, name := range names {
+		specs = append(specs, f.spec(name, supervisor.Permanent))
+	}
+	return specs
+}
+
+// failNow makes the running worker name fail with err.
+func (f *fleet) failNow(t *testing.T, name string, err error) {
+	t.Helper()
+	select {
+	case f.fail[name] <- err:
+	case <-time.After(time.Second):
+		t.Fatalf("%s is not running", name)
+	}
+}
+
+// started returns the names started so far, in order.
+func (f *fleet) started() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.starts)
+}
+
+// count returns how many runs of name have started.
+func (f *fleet) count(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.runs[name]
+}
+
+// eventually fails the test if cond doesn't become true within two seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// states returns "name=state/restarts" for every child.
+func states(s *supervisor.Supervisor) string {
+	var out []string
+	for _, c := range s.Status() {
+		out = append(out, fmt.Sprintf("%s=%v/%d", c.Name, c.State, c.Restarts))
+	}
+	return strings.Join(out, " ")
+}
+
+// fast restarts right away and never gives up within a test.
+var fast = supervisor.Config{
+	MaxRestarts: 100,
+	Period:      time.Minute,
+	Backoff:     supervisor.Backoff{Base: time.Millisecond},
+}
+
+func TestStrategies(t *testing.T) {
+	tests := []struct {
+		strategy supervisor.Strategy
+		want     string
+		starts   []string // After the initial a, b, c.
+	}{
+		{supervisor.OneForOne, "a=running/0 b=running/1 c=running/0", []string{"b"}},
+		{supervisor.OneForAll, "a=running/1 b=running/1 c=running/1", []string{"a", "b", "c"}},
+		{supervisor.RestForOne, "a=running/0 b=running/1 c=running/1", []string{"b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.strategy), func(t *testing.T) {
+			f := newFleet("a", "b", "c")
+			cfg := fast
+			cfg.Strategy = tt.strategy
+			s := supervisor.New(f.config(cfg), f.permanent("a", "b", "c")...)
+
+			eventually(t, "the first starts", func() bool { return len(f.started()) == 3 })
+			f.failNow(t, "b", errors.New("b broke"))
+			eventually(t, "the restarts", func() bool { return states(s) == tt.want })
+
+			if got := f.started()[3:]; !slices.Equal(got, tt.starts) {
+				t.Errorf("restarted %q, want %q", got, tt.starts)
+			}
+			if err := s.Status()[1].LastError; err == nil || err.Error() != "b broke" {
+				t.Errorf("b's LastError = %v, want b broke", err)
+			}
+
+			if err := s.Stop(); err != nil {
+				t.Errorf("Stop() = %v", err)
+			}
+			if got := states(s); strings.Count(got, "stopped") != 3 {
+				t.Errorf("after Stop: %s, want every child stopped", got)
+			}
+		})
+	}
+}
+
+func TestObsoleteRestartIsDropped(t *testing.T) {
+	// c fails and is scheduled for a restart; a fails before it is due
+	// and takes b and c with it. c's own restart must not start it
+	// ahead of a and b, which it depends on.
+	f := newFleet("a", "b", "c")
+	cfg := fast
+	cfg.Strategy = supervisor.RestForOne
+	cfg.Backoff = supervisor.Backoff{Base: 50 * time.Millisecond}
+	s := supervisor.New(f.config(cfg), f.permanent("a", "b", "c")...)
+	defer s.Stop()
+
+	eventually(t, "the first starts", func() bool { return len(f.started()) == 3 })
+	f.failNow(t, "c", errors.New("c broke"))
+	eventually(t, "c's restart to be scheduled", func() bool { return s.Status()[2].State == supervisor.Restarting })
+	f.failNow(t, "a", errors.New("a broke"))
+	eventually(t, "the restarts", func() bool {
+		return states(s) == "a=running/1 b=running/1 c=running/1"
+	})
+
+	time.Sleep(100 * time.Millisecond) // Past the obsolete restart.
+	if got := f.started()[3:]; !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("restarted %q, want a, b, c in order", got)
+	}
+}
+
+func TestRestartPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		restart   supervisor.Restart
+		fail      error
+		wantState supervisor.State
+		wantRuns  int
+		wantErr   string
+	}{
+		{"permanent, error", supervisor.Permanent, errors.New("oops"), supervisor.Running, 2, "oops"},
+		{"permanent, panic", supervisor.Permanent, errPanic, supervisor.Running, 2, "child panicked: boom"},
+		{"permanent, early return", supervisor.Permanent, nil, supervisor.Running, 2, "returned before being stopped"},
+		{"transient, error", supervisor.Transient, errors.New("oops"), supervisor.Running, 2, "oops"},
+		{"transient, done", supervisor.Transient, nil, supervisor.Stopped, 1, ""},
+		{"temporary, error", supervisor.Temporary, errors.New("oops"), supervisor.Failed, 1, "oops"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFleet("w")
+			s := supervisor.New(f.config(fast), f.spec("w", tt.restart))
+			defer s.Stop()
+
+			eventually(t, "the first start", func() bool { return f.count("w") == 1 })
+			f.failNow(t, "w", tt.fail)
+			eventually(t, "the final state", func() bool {
+				c := s.Status()[0]
+				return c.State == tt.wantState && f.count("w") == tt.wantRuns
+			})
+
+			c := s.Status()[0]
+			if c.Restarts != tt.wantRuns-1 {
+				t.Errorf("Restarts = %d, want %d", c.Restarts, tt.wantRuns-1)
+			}
+			switch {
+			case tt.wantErr == "" && c.LastError != nil,
+				tt.wantErr != "" && (c.LastError == nil || !strings.Contains(c.LastError.Error(), tt.wantErr)):
+				t.Errorf("LastError = %v, want %q", c.LastError, tt.wantErr)
+			}
+			if tt.fail == errPanic {
+				var pe *supervisor.PanicError
+				if !errors.As(c.LastError, &pe) || pe.Value != "boom" {
+					t.Errorf("LastError = %#v, want a *PanicError", c.LastError)
+				}
+			}
+		})
+	}
+}
+
+func TestTooManyRestarts(t *testing.T) {
+	f := newFleet("steady", "flaky")
+	cfg := fast
+	cfg.MaxRestarts = 2
+	s := supervisor.New(f.config(cfg), f.permanent("steady", "flaky")...)
+
+	errFlaky := errors.New("flaky broke")
+	for i := 0; i < 3; i++ {
+		eventually(t, "flaky to run", func() bool { return f.count("flaky") == i+1 })
+		f.failNow(t, "flaky", errFlaky)
+	}
+
+	err := s.Wait()
+	if !errors.Is(err, supervisor.ErrTooManyRestarts) || !errors.Is(err, errFlaky) {
+		t.Errorf("Wait() = %v, want ErrTooManyRestarts wrapping the last failure", err)
+	}
+	if got := states(s); got != "steady=stopped/0 flaky=failed/2" {
+		t.Errorf("status %s, want steady stopped and flaky failed", got)
+	}
+	if err := s.Stop(); !errors.Is(err, supervisor.ErrTooManyRestarts) {
+		t.Errorf("Stop() = %v, want the same error as Wait", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	const period = 100 * time.Millisecond
+	f := newFleet("w")
+	s := supervisor.New(f.config(supervisor.Config{
+		MaxRestarts: 100,
+		Period:      period,
+		Backoff:     supervisor.Backoff{Base: 5 * time.Millisecond, Multiplier: 10, Max: time.Second},
+	}), f.spec("w", supervisor.Permanent))
+	defer s.Stop()
+
+	// failAndWait fails w and returns how long its restart took.
+	failAndWait := func(runs int) time.Duration {
+		t.Helper()
+		start := time.Now()
+		f.failNow(t, "w", errors.New("oops"))
+		eventually(t, "the restart", func() bool { return f.count("w") == runs })
+		return time.Since(start)
+	}
+
+	eventually(t, "the first start", func() bool { return f.count("w") == 1 })
+
+	// Failing right after every start: 5ms, then 50ms, then 500ms.
+	for i, want := range []time.Duration{5 * time.Millisecond, 50 * time.Millisecond, 500 * time.Millisecond} {
+		if d := failAndWait(i + 2); d < want {
+			t.Errorf("restart %d after %v, want at least %v", i+1, d, want)
+		}
+	}
+
+	// After a whole Period of work the count starts over: 5ms, not 1s.
+	time.Sleep(period)
+	if d := failAndWait(5); d >= 500*time.Millisecond {
+		t.Errorf("restart after a Period of work took %v, want the backoff reset", d)
+	}
+}
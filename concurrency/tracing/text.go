@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// TextHandler is a slog.Handler rendering events the way the examples
+// used to print them:
+//
+//	[Main]: Waiting for merged data...
+//	   [Merge Goroutine]: ch1 closed, setting to nil.
+//
+// Each line is the role's Prefix followed by the message. The other
+// attributes and the timestamp are for the JSON output. Groups are
+// ignored.
+type TextHandler struct {
+	mu    *sync.Mutex // Shared by the handlers derived with WithAttrs.
+	w     io.Writer
+	attrs []slog.Attr
+}
+
+// NewTextHandler returns a TextHandler writing to w.
+func NewTextHandler(w io.Writer) *TextHandler {
+	return &TextHandler{mu: new(sync.Mutex), w: w}
+}
+
+// Enabled implements slog.Handler: everything at Info and above is printed.
+func (h *TextHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+// Handle implements slog.Handler.
+func (h *TextHandler) Handle(_ context.Context, r slog.Record) error {
+	prefix := ""
+	find := func(a slog.Attr) bool {
+		if a.Key == PrefixKey {
+			prefix = a.Value.String()
+			return false
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		find(a)
+	}
+	r.Attrs(find)
+
+	line := make([]byte, 0, len(prefix)+len(r.Message)+1)
+	line = append(line, prefix...)
+	line = append(line, r.Message...)
+	line = append(line, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(line)
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *TextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &TextHandler{mu: h.mu, w: h.w, attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *TextHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+// --- Main Function ---
+
+// main runs tiny tasks through a work-stealing pool once. BenchmarkExecutor
+// in concurrency/pool compares it with the channel-based pool over several
+// task sizes:
+//
+//	go test -bench Executor -benchmem ./concurrency/pool
+func main() {
+	cpus := runtime.GOMAXPROCS(0)
+	const tasks = 20_000
+
+	// A taskCPU-style task, but tiny: the cost of handing it to a worker
+	// is what matters here.
+	p := pool.NewStealing(context.Background(), cpus, func(_ context.Context, v int) (int, error) {
+		sum := 0
+		for i := 0; i < 100; i++ {
+			sum += (i * v) % 255
+		}
+		return sum & 1, nil
+	})
+
+	fmt.Printf("--- %d tiny tasks (100 iterations), pool size %d ---\n", tasks, cpus)
+	start := time.Now()
+	go func() {
+		defer p.Close()
+		for i := 0; i < tasks; i++ {
+			if p.Submit(i) != nil {
+				return
+			}
+		}
+	}()
+
+	count := 0
+	for range p.Results() {
+		count++
+	}
+	if err := p.Wait(); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("work stealing: %d results in %v\n", count, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+// usage is printed by -h and on invalid flags.
+const usage = `Usage: go run ./concurrency/worker-pool-cli [flags] [file ...]
+
+Streams every file (or stdin, if there is none or the name is "-")
+through the worker pool and prints a summary.
+
+Every framing but chunks cuts the input into records of at most
+-max-record bytes; a longer one is an error. Chunks are raw 1024-byte
+reads that may split a word or a match, so -task words and -task match
+refuse them.
+
+Examples:
+  go run ./concurrency/worker-pool-cli -task words /var/log/syslog
+  cat app.log | go run ./concurrency/worker-pool-cli -task match -pattern 'ERROR|FATAL'
+  go run ./concurrency/worker-pool-cli -framing delim -delim '|' -task checksum data.txt
+  go run ./concurrency/worker-pool-cli -max-record 16777216 -task lines huge-lines.json
+
+Flags:
+`
+
+// --- Built-in Tasks ---
+
+// errChunksSplit refuses the tasks that raw reads would miscount: a
+// 1024-byte chunk can end in the middle of a word or of a match.
+var errChunksSplit = errors.New("-task words and -task match need whole records: use -framing lines, delim, length or fixed")
+
+// newTask returns the named task. Every task maps one record (or chunk)
+// to a number, and the pool sums those numbers.
+func newTask(name, framing string, pattern *regexp.Regexp) (pool.Func[[]byte, int], error) {
+	var fn func([]byte) int
+
+	switch name {
+	case "lines":
+		if framing == "lines" {
+			// The framing already cut the input into lines.
+			fn = func([]byte) int { return 1 }
+		} else {
+			fn = func(b []byte) int { return bytes.Count(b, []byte("\n")) }
+		}
+	case "words":
+		if framing == "chunks" {
+			return nil, errChunksSplit
+		}
+		fn = func(b []byte) int { return len(bytes.Fields(b)) }
+	case "checksum":
+		// The sum of the CRC-32 of every record: unlike a CRC of the whole
+		// input, it doesn't depend on the order the workers finish in.
+		fn = func(b []byte) int { return int(crc32.ChecksumIEEE(b)) }
+	case "match":
+		if pattern == nil {
+			return nil, errors.New("-task match needs -pattern")
+		}
+		if framing == "chunks" {
+			return nil, errChunksSplit
+		}
+		fn = func(b []byte) int {
+			if pattern.Match(b) {
+				return 1
+			}
+			return 0
+		}
+	default:
+		return nil, fmt.Errorf("unknown task %q", name)
+	}
+
+	return func(_ context.Context, b []byte) (int, error) {
+		return fn(b), nil
+	}, nil
+}
+
+// framingOptions turns the -framing, -delim, -size and -max-record flags
+// into ReadOptions.
+func framingOptions(framing, delim string, size, maxRecord int) ([]pool.ReadOption, error) {
+	if framing == "chunks" {
+		return nil, nil // Raw pool.ChunkSize reads: no records, no limit.
+	}
+	if maxRecord < 1 {
+		return nil, errors.New("-max-record must be at least 1")
+	}
+	opt, err := framingOption(framing, delim, size)
+	if err != nil {
+		return nil, err
+	}
+	return []pool.ReadOption{opt, pool.WithMaxRecordSize(maxRecord)}, nil
+}
+
+// framingOption turns the -framing, -delim and -size flags into a ReadOption.
+func framingOption(framing, delim string, size int) (pool.ReadOption, error) {
+	switch framing {
+	case "lines":
+		return pool.WithFraming(pool.Lines()), nil
+	case "delim":
+		if len(delim) != 1 {
+			return nil, errors.New("-delim must be a single byte")
+		}
+		return pool.WithFraming(pool.Delimited(delim[0])), nil
+	case "length":
+		return pool.WithFraming(pool.LengthPrefixed()), nil
+	case "fixed":
+		if size < 1 {
+			return nil, errors.New("-framing fixed needs -size")
+		}
+		return pool.WithFraming(pool.FixedSize(size)), nil
+	default:
+		return nil, fmt.Errorf("unknown framing %q", framing)
+	}
+}
+
+// --- Main ---
+
+func main() {
+	flags := flag.NewFlagSet("worker-pool-cli", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	framing := flags.String("framing", "lines", "how to cut the input: chunks, lines, delim, length or fixed")
+	delim := flags.String("delim", "|", "record delimiter for -framing delim")
+	size := flags.Int("size", 0, "record size in bytes for -framing fixed")
+	maxRecord := flags.Int("max-record", 1<<20, "largest record in bytes, for every framing but chunks")
+	n := flags.Int("n", runtime.GOMAXPROCS(0), "pool size")
+	taskName := flags.String("task", "lines", "task to run: lines, words, checksum or match")
+	patternText := flags.String("pattern", "", "regular expression for -task match")
+	flags.Parse(os.Args[1:])
+
+	if err := run(flags.Args(), *framing, *delim, *size, *maxRecord, *n, *taskName, *patternText); err != nil {
+		fmt.Fprintf(os.Stderr, "worker-pool-cli: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run processes every input and prints one line per input plus a total.
+func run(inputs []string, framing, delim string, size, maxRecord, n int, taskName, patternText string) error {
+	if n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+
+	var pattern *regexp.Regexp
+	if patternText != "" {
+		var err error
+		if pattern, err = regexp.Compile(patternText); err != nil {
+			return err
+		}
+	}
+
+	task, err := newTask(taskName, framing, pattern)
+	if err != nil {
+		return err
+	}
+	opts := []pool.ReadOption{pool.WithBufferRecycling()} // No task keeps its buffer.
+	framingOpts, err := framingOptions(framing, delim, size, maxRecord)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, framingOpts...)
+
+	// Ctrl-C cancels the pool instead of killing the process mid-output.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	var total, tasks int64
+	start := time.Now()
+	for _, name := range inputs {
+		r, closeInput, err := open(name)
+		if err != nil {
+			return err
+		}
+
+		fileStart := time.Now()
+		result, summary, err := pool.ReadContext(ctx, r, n, task, opts...)
+		closeInput()
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("%s: a record is longer than -max-record %d: %w", name, maxRecord, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		total += result
+		tasks += int64(summary.Succeeded)
+		fmt.Printf("%-30s %s  %8d tasks  %v\n", name, format(taskName, result), summary.Succeeded,
+			time.Since(fileStart).Round(time.Microsecond))
+	}
+
+	if len(inputs) > 1 {
+		fmt.Printf("%-30s %s  %8d tasks  %v\n", "total", format(taskName, total), tasks,
+			time.Since(start).Round(time.Microsecond))
+	}
+	return nil
+}
+
+// open opens a named input; "-" is stdin, which must not be closed.
+func open(name string) (io.Reader, func(), error) {
+	if name == "-" {
+		return os.Stdin, func() {}, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
+
+// format prints a task result: checksums in hex, everything else as a count.
+func format(taskName string, v int64) string {
+	if taskName == "checksum" {
+		return fmt.Sprintf("%s %08x", taskName, uint32(v))
+	}
+	return fmt.Sprintf("%s %10d", taskName, v)
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"testing/iotest"
 	"time"
+
+	"advanced-concepts/concurrency/pool"
 )
 
 // read implements the worker pool pattern from your book.
@@ -85,50 +95,464 @@ func taskIO(b []byte) int {
 	return 1
 }
 
+// taskIOContext is taskIO for pool.ReadContext: the "network call"
+// gives up as soon as the pool is cancelled.
+func taskIOContext(ctx context.Context, b []byte) (int, error) {
+	select {
+	case <-time.After(50 * time.Millisecond):
+		return 1, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// slowHead returns its input after 100ms for task 0 and 5ms for the others,
+// so that the head of the input finishes last.
+func slowHead(i int) int {
+	if i == 0 {
+		time.Sleep(100 * time.Millisecond)
+	} else {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return i
+}
+
+// packet is the record every demo input is made of.
+const packet = "some-data-packet"
+
+// taskIntact reports whether the task received exactly one whole packet
+// (with or without its '|' delimiter). It is cheap, so it isolates the
+// effect of framing from the cost of the work itself.
+func taskIntact(_ context.Context, b []byte) (int, error) {
+	if string(bytes.TrimSuffix(b, []byte("|"))) == packet {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// lengthPrefixed encodes count packets for pool.LengthPrefixed.
+func lengthPrefixed(count int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < count; i++ {
+		binary.Write(&buf, binary.BigEndian, uint32(len(packet)))
+		buf.WriteString(packet)
+	}
+	return buf.Bytes()
+}
+
+// taskFragile panics on a "panic" packet and hangs, ignoring its context,
+// on a "hang" packet.
+func taskFragile(_ context.Context, b []byte) (int, error) {
+	switch string(b) {
+	case "panic":
+		var m map[string]int
+		m["boom"]++ // assignment to entry in nil map
+	case "hang":
+		time.Sleep(300 * time.Millisecond)
+	}
+	return 1, nil
+}
+
+// weightedPacket is a task input that declares its cost to a
+// pool.Weighted pool by implementing pool.Coster.
+type weightedPacket struct {
+	heavy bool
+}
+
+func (w weightedPacket) Cost() int64 {
+	if w.heavy {
+		return 5
+	}
+	return 1
+}
+
+// errUnavailable is the transient failure taskFlaky simulates.
+var errUnavailable = errors.New("service unavailable")
+
+// taskFlaky simulates an unreliable API: "flaky" fails twice before it
+// succeeds, "down" always fails with a transient error and "broken" fails
+// with an error that is not worth retrying.
+func taskFlaky() pool.Func[string, string] {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+
+	return func(_ context.Context, v string) (string, error) {
+		mu.Lock()
+		calls[v]++
+		n := calls[v]
+		mu.Unlock()
+
+		switch {
+		case v == "broken":
+			return "", errors.New("invalid request")
+		case v == "down", v == "flaky" && n <= 2:
+			return "", errUnavailable
+		}
+		return fmt.Sprintf("%s (attempt %d)", v, n), nil
+	}
+}
+
+// errBadPacket is what taskFail returns for the chunk it refuses.
+var errBadPacket = errors.New("bad packet")
+
+// taskFail succeeds for the first chunk and fails for every other one.
+func taskFail() func(context.Context, []byte) (int, error) {
+	var calls int64
+	return func(ctx context.Context, b []byte) (int, error) {
+		if atomic.AddInt64(&calls, 1) > 1 {
+			return 0, errBadPacket
+		}
+		return taskIOContext(ctx, b)
+	}
+}
+
 // --- Main Function to Run the Tests ---
 
-func main() {
-	// Create some sample data with 100 "tasks" (separated by '|').
-	// A "task" is just some data we read. Our reader will read 1024 bytes
-	// at a time, so we need enough data.
-	taskData := strings.Repeat("some-data-packet|", 200) // ~4KB of data
+// workload is one row of the table main() runs: a task, the pool size the
+// book recommends for it and the function that drives the pool.
+type workload struct {
+	name string
+	task func([]byte) int
+	size int
+	run  func(r io.Reader, taskFunc func([]byte) int, n int) (int64, error)
+}
 
-	// --- Test 1: CPU-Bound Workload ---
-	fmt.Println("--- Starting CPU-Bound Test ---")
+// taskData is some sample data with 200 "tasks" (separated by '|').
+// A "task" is just some data we read. Our reader will read 1024 bytes
+// at a time, so we need enough data.
+var taskData = strings.Repeat("some-data-packet|", 200) // ~4KB of data
 
-	// As the book recommends: pool size = number of logical CPUs.
+func main() {
+	// As the book recommends: pool size = number of logical CPUs for
+	// CPU-bound work, and an arbitrary *high* number, much larger than
+	// GOMAXPROCS, for I/O-bound work (50 "simultaneous" I/O calls).
 	cpuPoolSize := runtime.GOMAXPROCS(0)
-	fmt.Printf("Workload: CPU-Bound\nPool Size: %d (runtime.GOMAXPROCS)\n", cpuPoolSize)
+	ioPoolSize := 50
 
-	cpuReader := strings.NewReader(taskData)
-	startCPU := time.Now()
+	workloads := []workload{
+		{name: "CPU-Bound / read()", task: taskCPU, size: cpuPoolSize, run: read},
+		{name: "CPU-Bound / pool.Read()", task: taskCPU, size: cpuPoolSize, run: pool.Read},
+		{name: "I/O-Bound / read()", task: taskIO, size: ioPoolSize, run: read},
+		{name: "I/O-Bound / pool.Read()", task: taskIO, size: ioPoolSize, run: pool.Read},
+	}
 
-	countCPU, err := read(cpuReader, taskCPU, cpuPoolSize)
+	// A single run of each; BenchmarkRead in main_test.go gives stable
+	// numbers: go test -bench . -benchmem ./concurrency/worker-pool-pattern
+	for _, wl := range workloads {
+		fmt.Printf("--- %s (pool size %d) ---\n", wl.name, wl.size)
 
-	fmt.Printf("CPU-Bound Test complete in: %v\n", time.Since(startCPU))
-	fmt.Printf("Total tasks processed: %d\n", countCPU)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		// One task per 1024-byte chunk.
+		start := time.Now()
+		count, err := wl.run(strings.NewReader(taskData), wl.task, wl.size)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		fmt.Printf("Total tasks processed: %d in %v\n\n", count, time.Since(start).Round(time.Millisecond))
 	}
 
-	fmt.Println("\n---------------------------------\n")
+	// --- Framing ---
+	// Raw 1024-byte reads cut packets in half; with framing every task
+	// gets exactly one packet, so "tasks processed" equals the record count.
+	framings := []struct {
+		name  string
+		input []byte
+		opts  []pool.ReadOption
+	}{
+		{"raw 1024-byte chunks", []byte(taskData), nil},
+		{"Delimited('|')", []byte(taskData), []pool.ReadOption{pool.WithFraming(pool.Delimited('|'))}},
+		{"Lines()", []byte(strings.Repeat(packet+"\n", 200)), []pool.ReadOption{pool.WithFraming(pool.Lines())}},
+		{"LengthPrefixed()", lengthPrefixed(200), []pool.ReadOption{pool.WithFraming(pool.LengthPrefixed())}},
+		{"FixedSize(17)", []byte(taskData), []pool.ReadOption{pool.WithFraming(pool.FixedSize(len(packet) + 1))}},
+	}
 
-	// --- Test 2: I/O-Bound Workload ---
-	fmt.Println("--- Starting I/O-Bound Test ---")
+	fmt.Println("--- Framing (200 packets) ---")
+	for _, f := range framings {
+		intact, _, err := pool.ReadContext(context.Background(), bytes.NewReader(f.input), cpuPoolSize, taskIntact, f.opts...)
+		if err != nil {
+			fmt.Printf("%-22s Error: %v\n", f.name, err)
+			continue
+		}
+		fmt.Printf("%-22s intact packets: %d\n", f.name, intact)
+	}
+	fmt.Println()
 
-	// We pick an arbitrary *high* number, much larger than GOMAXPROCS.
-	// We can run 50 "simultaneous" I/O calls.
-	ioPoolSize := 50
-	fmt.Printf("Workload: I/O-Bound (50ms wait per task)\nPool Size: %d\n", ioPoolSize)
+	// --- Buffer Recycling ---
+	// pool.WithBufferRecycling saves the producer one allocation per task;
+	// TestBufferRecyclingSavesAllocations checks it, and BenchmarkReadContext
+	// in concurrency/pool measures it per task:
+	// go test -bench ReadContext -benchmem ./concurrency/pool
 
-	ioReader := strings.NewReader(taskData)
-	startIO := time.Now()
+	// --- Ordered Delivery ---
+	// Task 0 is slow, every other task is quick. Unordered, the results
+	// come back as they finish; with pool.Ordered they come back in input
+	// order, and the producer blocks once the window is full of results
+	// waiting for task 0.
+	orderedCases := []struct {
+		name string
+		opts []pool.Option
+	}{
+		{"unordered", nil},
+		{"pool.Ordered(8)", []pool.Option{pool.Ordered(8)}},
+	}
 
-	countIO, err := read(ioReader, taskIO, ioPoolSize)
+	fmt.Println("--- Ordered delivery (task 0 takes 100ms, others 5ms) ---")
+	for _, c := range orderedCases {
+		p := pool.New(4, slowHead, c.opts...)
 
-	fmt.Printf("I/O-Bound Test complete in: %v\n", time.Since(startIO))
-	fmt.Printf("Total tasks processed: %d\n", countIO)
+		var blocked time.Duration
+		go func() {
+			defer p.Close()
+			for i := 0; i < 20; i++ {
+				start := time.Now()
+				p.Submit(i)
+				blocked += time.Since(start)
+			}
+		}()
+
+		var order []int
+		for v := range p.Results() {
+			order = append(order, v)
+		}
+		p.Wait()
+
+		// 'blocked' is safe to read: it was written before p.Close().
+		fmt.Printf("%-16s producer blocked %4dms, results: %v\n", c.name, blocked.Milliseconds(), order)
+	}
+	fmt.Println()
+
+	// --- Panics and Timeouts ---
+	// One packet makes the task panic and one makes it hang. Neither takes
+	// the process or the pool down: both are reported in the summary and
+	// the other packets are still counted.
+	fmt.Println("--- Panics and timeouts (TaskTimeout 50ms) ---")
+	count, summary, err := pool.ReadContext(context.Background(),
+		strings.NewReader("ok|ok|panic|ok|hang|ok"), ioPoolSize, taskFragile,
+		pool.WithFraming(pool.Delimited('|')),
+		pool.WithPoolOptions(pool.TaskTimeout(50*time.Millisecond)),
+	)
+	fmt.Printf("Total tasks processed: %d, error: %v\n", count, err)
+	fmt.Printf("Succeeded: %d, panicked: %d, timed out: %d\n", summary.Succeeded, summary.Panicked, summary.TimedOut)
+	for _, err := range summary.Errors {
+		var pe *pool.PanicError
+		if errors.As(err, &pe) {
+			fmt.Printf("  %v (stack: %d bytes)\n", err, len(pe.Stack))
+			continue
+		}
+		fmt.Printf("  %v\n", err)
+	}
+	fmt.Println()
+
+	// --- Autoscaling ---
+	// The same pool configuration for both workloads: it should stay near
+	// GOMAXPROCS for taskCPU and grow towards Max for taskIO.
+	fmt.Printf("--- Autoscaling (1000 packets, Min 1, Max 64, GOMAXPROCS %d) ---\n", cpuPoolSize)
+	for _, wl := range []struct {
+		name string
+		task func([]byte) int
+	}{{"CPU-Bound", taskCPU}, {"I/O-Bound", taskIO}} {
+		p := pool.New(1, wl.task, pool.Autoscale(pool.AutoscaleConfig{
+			Min:      1,
+			Max:      64,
+			Interval: 100 * time.Millisecond,
+		}))
+
+		start := time.Now()
+		go func() {
+			defer p.Close()
+			for i := 0; i < 1000; i++ {
+				p.Submit([]byte(packet))
+			}
+		}()
+
+		var count int64
+		for v := range p.Results() {
+			count += int64(v)
+		}
+		p.Wait()
+
+		var sizes []int
+		for _, s := range p.ScaleReport() {
+			sizes = append(sizes, s.Workers)
+		}
+		fmt.Printf("%s: %d tasks in %v\n", wl.name, count, time.Since(start).Round(time.Millisecond))
+		fmt.Printf("  workers every 100ms: %v\n", sizes)
+	}
+	fmt.Println()
+
+	// --- Instrumentation ---
+	// Tuning the pool size with more than the total wall time: the same
+	// I/O-bound run with a small and a large pool.
+	fmt.Println("--- Instrumentation (200 I/O-bound packets) ---")
+	var metrics *pool.MemoryMetrics
+	for _, size := range []int{5, ioPoolSize} {
+		metrics = pool.NewMemoryMetrics()
+		start := time.Now()
+		pool.ReadContext(context.Background(), strings.NewReader(taskData), size, taskIOContext,
+			pool.WithFraming(pool.Delimited('|')),
+			pool.WithPoolOptions(pool.Instrument(metrics)),
+		)
+		fmt.Printf("Pool size %2d: %v, worker utilisation %.0f%%\n",
+			size, time.Since(start).Round(time.Millisecond), 100*metrics.Utilisation())
+	}
+
+	// Scrape the last run the way Prometheus would.
+	srv := httptest.NewServer(metrics)
+	resp, err := http.Get(srv.URL)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
+	} else {
+		fmt.Printf("GET %s:\n", srv.URL)
+		io.Copy(os.Stdout, resp.Body)
+		resp.Body.Close()
+	}
+	srv.Close()
+	fmt.Println()
+
+	// --- Retries ---
+	// "flaky" succeeds on its third run, "down" never does and "broken"
+	// isn't worth retrying. TestRetrySchedule in concurrency/pool checks
+	// the schedule against a ManualClock.
+	fmt.Println("--- Retries (MaxAttempts 3, backoff 10ms x2) ---")
+	p := pool.WithContext(context.Background(), 4, taskFlaky(), pool.Retry(pool.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     pool.Backoff{Base: 10 * time.Millisecond, Max: 100 * time.Millisecond, Jitter: 0.2},
+		Retryable:   func(err error) bool { return errors.Is(err, errUnavailable) },
+	}))
+	go func() {
+		defer p.Close()
+		for _, v := range []string{"a", "flaky", "b", "down", "broken"} {
+			p.Submit(v)
+		}
+	}()
+
+	for v := range p.Results() {
+		fmt.Printf("Result: %s\n", v)
+	}
+	for dl := range p.DeadLetters() {
+		fmt.Printf("Dead letter: %q after %d attempt(s): %v\n", dl.Value, dl.Attempts, dl.Err)
+	}
+	p.Wait()
+
+	summary = p.Summary()
+	fmt.Printf("Succeeded: %d, retried: %d, dead-lettered: %d\n\n",
+		summary.Succeeded, summary.Retried, summary.DeadLettered)
+
+	// --- Rate and Concurrency Limits ---
+	// 50 workers could start 1000 I/O tasks per second; a downstream
+	// quota of 400/s must win over the pool size.
+	fmt.Println("--- Rate limiting (200 I/O-bound packets, pool size 50) ---")
+	for _, limit := range []float64{0, 400} {
+		var opts []pool.Option
+		if limit > 0 {
+			opts = append(opts, pool.RateLimit(pool.NewTokenBucket(limit, 20)))
+		}
+		start := time.Now()
+		pool.ReadContext(context.Background(), strings.NewReader(taskData), ioPoolSize, taskIOContext,
+			pool.WithFraming(pool.Delimited('|')),
+			pool.WithPoolOptions(opts...),
+		)
+		tput := 200 / time.Since(start).Seconds()
+		if limit == 0 {
+			fmt.Printf("unlimited:     %6.0f tasks/s\n", tput)
+		} else {
+			fmt.Printf("limit %4.0f/s: %6.0f tasks/s\n", limit, tput)
+		}
+	}
+
+	// A weighted semaphore of 10 units: heavy packets cost 5, light ones 1,
+	// and the total cost in flight never exceeds 10 despite 50 workers.
+	sem := pool.NewSemaphore(10)
+	var inFlight, maxInFlight int64
+	wp := pool.New(ioPoolSize, func(w weightedPacket) int {
+		cur := atomic.AddInt64(&inFlight, w.Cost())
+		for m := atomic.LoadInt64(&maxInFlight); cur > m && !atomic.CompareAndSwapInt64(&maxInFlight, m, cur); {
+			m = atomic.LoadInt64(&maxInFlight)
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt64(&inFlight, -w.Cost())
+		return 1
+	}, pool.Weighted(sem))
+	go func() {
+		defer wp.Close()
+		for i := 0; i < 100; i++ {
+			wp.Submit(weightedPacket{heavy: i%4 == 0})
+		}
+	}()
+	var weighted int64
+	for v := range wp.Results() {
+		weighted += int64(v)
+	}
+	wp.Wait()
+	fmt.Printf("Weighted(10): %d tasks, max cost in flight: %d\n\n", weighted, maxInFlight)
+
+	// --- Priorities and Fairness ---
+	// "urgent" keeps the top level busy and "bulk-a" floods the bottom one,
+	// yet "reports" and "bulk-b" still get served early on.
+	// TestPriorityPoolFairness in concurrency/pool checks the service order.
+	fmt.Println("--- Priorities and fairness (first 30 of 210 tasks, 1 worker) ---")
+	pp := pool.NewPriority(context.Background(), 1, func(_ context.Context, tenant string) (string, error) {
+		time.Sleep(time.Millisecond)
+		return tenant, nil
+	}, pool.PriorityConfig{Levels: 3, StarvationLimit: 8})
+
+	go func() {
+		defer pp.Close()
+		for i := 0; i < 50; i++ {
+			pp.Submit("urgent", 0, "urgent")
+			pp.Submit("reports", 1, "reports")
+			pp.Submit("bulk-a", 2, "bulk-a")
+			pp.Submit("bulk-a", 2, "bulk-a")
+		}
+		for i := 0; i < 10; i++ {
+			pp.Submit("bulk-b", 2, "bulk-b")
+		}
+	}()
+
+	var order []string
+	for tenant := range pp.Results() {
+		order = append(order, tenant)
+	}
+	pp.Wait()
+	fmt.Println(strings.Join(order[:min(30, len(order))], " "))
+	fmt.Println()
+
+	// --- Errors and Cancellation ---
+	// On a failing reader the book's read() returns with its workers still
+	// blocked on the channel; pool.ReadContext doesn't leave any behind.
+	// TestFailuresDontLeak in main_test.go checks the goroutine counts.
+	brokenReader := func() io.Reader {
+		return io.MultiReader(
+			strings.NewReader(taskData[:2*pool.ChunkSize]),
+			iotest.ErrReader(errors.New("connection reset")),
+		)
+	}
+
+	failures := []struct {
+		name string
+		run  func() (int64, error)
+	}{
+		{"reader error / read()", func() (int64, error) {
+			return read(brokenReader(), taskIO, ioPoolSize)
+		}},
+		{"reader error / pool.ReadContext()", func() (int64, error) {
+			count, _, err := pool.ReadContext(context.Background(), brokenReader(), ioPoolSize, taskIOContext)
+			return count, err
+		}},
+		{"task error / pool.ReadContext()", func() (int64, error) {
+			count, _, err := pool.ReadContext(context.Background(), strings.NewReader(taskData), ioPoolSize, taskFail())
+			return count, err
+		}},
+		{"timeout / pool.ReadContext()", func() (int64, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			count, _, err := pool.ReadContext(ctx, strings.NewReader(taskData), ioPoolSize, taskIOContext)
+			return count, err
+		}},
+	}
+
+	for _, f := range failures {
+		_, err := f.run()
+		fmt.Printf("%-34s Error: %v\n", f.name, err)
 	}
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"advanced-concepts/concurrency/pool"
+)
+
+// readers are the two implementations main() compares: the book's read()
+// and the pool package's Read.
+var readers = []struct {
+	name string
+	run  func(r io.Reader, taskFunc func([]byte) int, n int) (int64, error)
+}{
+	{"read", read},
+	{"pool.Read", pool.Read},
+}
+
+func TestRead(t *testing.T) {
+	// One task per 1024-byte chunk: 3400 bytes make 4 chunks.
+	const want = 4
+	for _, r := range readers {
+		t.Run(r.name, func(t *testing.T) {
+			got, err := r.run(strings.NewReader(taskData), func([]byte) int { return 1 }, 4)
+			if err != nil || got != want {
+				t.Errorf("%s() = %d, %v; want %d, nil", r.name, got, err, want)
+			}
+		})
+	}
+}
+
+// settledGoroutines gives goroutines that are on their way out a moment to
+// finish and returns how many are still running.
+func settledGoroutines(want int) int {
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return runtime.NumGoroutine()
+}
+
+func TestFailuresDontLeak(t *testing.T) {
+	const workers = 50
+	errReset := errors.New("connection reset")
+	brokenReader := func() io.Reader {
+		return io.MultiReader(
+			strings.NewReader(taskData[:2*pool.ChunkSize]),
+			iotest.ErrReader(errReset),
+		)
+	}
+
+	tests := []struct {
+		name       string
+		run        func() (int64, error)
+		wantErr    error
+		wantLeaked int
+	}{
+		{
+			// The book's read() returns on a reader error without closing
+			// the channel, so every worker stays blocked on it.
+			name:       "reader error / read()",
+			run:        func() (int64, error) { return read(brokenReader(), taskIO, workers) },
+			wantErr:    errReset,
+			wantLeaked: workers,
+		},
+		{
+			name: "reader error / pool.ReadContext()",
+			run: func() (int64, error) {
+				count, _, err := pool.ReadContext(context.Background(), brokenReader(), workers, taskIOContext)
+				return count, err
+			},
+			wantErr: errReset,
+		},
+		{
+			name: "task error / pool.ReadContext()",
+			run: func() (int64, error) {
+				count, _, err := pool.ReadContext(context.Background(), strings.NewReader(taskData), workers, taskFail())
+				return count, err
+			},
+			wantErr: errBadPacket,
+		},
+		{
+			name: "timeout / pool.ReadContext()",
+			run: func() (int64, error) {
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+				count, _, err := pool.ReadContext(ctx, strings.NewReader(taskData), workers, taskIOContext)
+				return count, err
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+			_, err := tt.run()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if leaked := settledGoroutines(before+tt.wantLeaked) - before; leaked != tt.wantLeaked {
+				t.Errorf("%d goroutines leaked, want %d", leaked, tt.wantLeaked)
+			}
+		})
+	}
+}
+
+// BenchmarkRead compares read() and pool.Read on both workloads, with the
+// pool sizes the book recommends: GOMAXPROCS for CPU-bound work and a much
+// larger number for I/O-bound work. Run it with
+//
+//	go test -bench Read -benchmem ./concurrency/worker-pool-pattern
+func BenchmarkRead(b *testing.B) {
+	workloads := []struct {
+		name string
+		task func([]byte) int
+		size int
+	}{
+		{"CPU-bound", taskCPU, runtime.GOMAXPROCS(0)},
+		{"I/O-bound", taskIO, 50},
+	}
+	for _, wl := range workloads {
+		for _, r := range readers {
+			b.Run(wl.name+"/"+r.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := r.run(strings.NewReader(taskData), wl.task, wl.size); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -1 +1,3 @@
 module advanced-concepts
+
+go 1.22